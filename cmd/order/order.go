@@ -3,13 +3,35 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"orderservice/pkg/checkout"
 	"orderservice/pkg/client/exchange"
 	"orderservice/pkg/client/payment"
 	"orderservice/pkg/client/product"
 	"orderservice/pkg/config"
+	"orderservice/pkg/discount"
+	"orderservice/pkg/download"
+	"orderservice/pkg/events"
+	"orderservice/pkg/health"
+	"orderservice/pkg/invoice"
+	"orderservice/pkg/ledger"
+	"orderservice/pkg/lifecycle"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/ratelimit"
 	"orderservice/pkg/repo/cart"
+	"orderservice/pkg/repo/dispute"
+	"orderservice/pkg/repo/entitlement"
+	"orderservice/pkg/repo/gift"
 	"orderservice/pkg/repo/order"
+	"orderservice/pkg/repo/wallet"
+	"orderservice/pkg/risk"
 	"orderservice/pkg/server"
+	"orderservice/pkg/serviceauth"
+	"orderservice/pkg/tax"
+	"orderservice/pkg/telemetry"
+	"orderservice/pkg/webhook"
 	"os"
 
 	"go.uber.org/zap"
@@ -18,6 +40,7 @@ import (
 func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
 
 	conf, err := config.LoadConfig()
 	if err != nil {
@@ -25,6 +48,18 @@ func main() {
 		os.Exit(-1)
 	}
 
+	tracer, err := newTracer(conf)
+	if err != nil {
+		logger.Error("error on creating tracer", zap.Error(err))
+		os.Exit(-1)
+	}
+	telemetry.SetDefault(tracer)
+
+	var m metrics.Metrics = metrics.Nop{}
+	if conf.MetricsEnabled {
+		m = metrics.NewPrometheus()
+	}
+
 	redisCartStorage, err := cart.NewRedisCartStorage(conf.RedisUrl)
 	if err != nil {
 		logger.Error("error on creating redis", zap.Error(err))
@@ -37,12 +72,320 @@ func main() {
 		os.Exit(-1)
 	}
 
-	productClient := product.NewProductClient(conf.ProductServiceUrl)
-	exchangeClient := exchange.NewExchangeClient(conf.ExchangeServiceUrl)
-	paymentClient := payment.NewPaymentClient(conf.PaymentServiceUrl)
+	pgEntitlementStorage, err := entitlement.NewPGEntitlementStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg entitlement store", zap.Error(err))
+		os.Exit(-1)
+	}
+
+	pgPromoStorage, err := discount.NewPGPromoStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg promo store", zap.Error(err))
+		os.Exit(-1)
+	}
+
+	pgInvoiceStorage, err := invoice.NewPGInvoiceStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg invoice store", zap.Error(err))
+		os.Exit(-1)
+	}
+
+	pgGiftStorage, err := gift.NewPGGiftStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg gift store", zap.Error(err))
+		os.Exit(-1)
+	}
+
+	pgWalletStorage, err := wallet.NewPGWalletStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg wallet store", zap.Error(err))
+		os.Exit(-1)
+	}
+
+	pgLedgerStorage, err := ledger.NewPGLedgerStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg ledger store", zap.Error(err))
+		os.Exit(-1)
+	}
+
+	pgDisputeStorage, err := dispute.NewPGDisputeStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg dispute store", zap.Error(err))
+		os.Exit(-1)
+	}
+
+	// order events of every instance, streamed to the buyers
+	broker := events.NewPGBroker(conf.PostgresqlUrl, logger)
+
+	invoiceBuilder := invoice.NewBuilder(conf.InvoiceNumberPrefix, invoice.Party{
+		Name:    conf.InvoiceSellerName,
+		Address: conf.InvoiceSellerAddress,
+		VATId:   conf.InvoiceSellerVATId,
+		Country: conf.InvoiceSellerCountry,
+	})
+
+	var signer serviceauth.Signer
+	if conf.ServiceHMACSigningKey != "" {
+		key, ok := conf.ServiceHMACKeys[conf.ServiceHMACSigningKey]
+		if !ok {
+			logger.Error("signing key is not in SERVICE_HMAC_KEYS", zap.String("key_id", conf.ServiceHMACSigningKey))
+			os.Exit(-1)
+		}
+		signer = serviceauth.NewHMACSigner(conf.ServiceId, conf.ServiceHMACSigningKey, key)
+	}
 
-	handler := server.NewHandler(logger, redisCartStorage, pgOrderStorage, productClient, exchangeClient, paymentClient)
-	srvr := server.NewServer(&handler, conf)
+	// each storage has a pool of its own
+	m.RegisterPool("redis", redisCartStorage.PoolStats)
+	m.RegisterPool("postgresql_order", pgOrderStorage.PoolStats)
+	m.RegisterPool("postgresql_entitlement", pgEntitlementStorage.PoolStats)
+	m.RegisterPool("postgresql_promo", pgPromoStorage.PoolStats)
+	m.RegisterPool("postgresql_invoice", pgInvoiceStorage.PoolStats)
+	m.RegisterPool("postgresql_gift", pgGiftStorage.PoolStats)
+	m.RegisterPool("postgresql_wallet", pgWalletStorage.PoolStats)
+	m.RegisterPool("postgresql_ledger", pgLedgerStorage.PoolStats)
+	m.RegisterPool("postgresql_dispute", pgDisputeStorage.PoolStats)
 
-	srvr.Listen()
+	productClient := product.NewProductClient(conf.ProductServiceUrl, signer, m)
+	exchangeClient := exchange.NewExchangeClient(conf.ExchangeServiceUrl, signer, m)
+	paymentClient := payment.NewPaymentClient(conf.PaymentServiceUrl, signer, m)
+
+	healthChecker := health.NewChecker(conf.HealthCheckTimeout, conf.HealthCacheTTL)
+	healthChecker.Register("redis", redisCartStorage.Ping)
+	healthChecker.Register("postgresql", pgOrderStorage.Ping)
+	if conf.HealthCheckDownstream {
+		healthChecker.Register("product", productClient.Ping)
+		healthChecker.Register("exchange", exchangeClient.Ping)
+		healthChecker.Register("payment", paymentClient.Ping)
+	}
+
+	var riskEvaluator risk.Evaluator = risk.AllowAll{}
+	var velocityStore *risk.RedisVelocityStore
+	var fingerprinter *risk.Fingerprinter
+	if conf.RiskEnabled {
+		fingerprinter, err = risk.NewFingerprinter(conf.RiskFingerprintKey)
+		if err != nil {
+			logger.Error("error on creating card fingerprinter", zap.Error(err))
+			os.Exit(-1)
+		}
+
+		rules, err := risk.LoadRules(conf.RiskRulesFile)
+		if err != nil {
+			logger.Error("error on loading risk rules", zap.Error(err))
+			os.Exit(-1)
+		}
+
+		velocityStore, err = risk.NewRedisVelocityStore(conf.RedisUrl, conf.RiskRetention)
+		if err != nil {
+			logger.Error("error on creating velocity store", zap.Error(err))
+			os.Exit(-1)
+		}
+		riskEvaluator = risk.NewRuleEngine(rules, velocityStore)
+	}
+
+	var taxCalculator tax.TaxCalculator = tax.NoTax{}
+	if conf.TaxEnabled {
+		rates, err := tax.LoadRates(conf.TaxRatesFile)
+		if err != nil {
+			logger.Error("error on loading tax rates", zap.Error(err))
+			os.Exit(-1)
+		}
+
+		taxCalculator, err = tax.NewTableCalculator(rates, tax.Mode(conf.TaxPriceMode))
+		if err != nil {
+			logger.Error("error on creating tax calculator", zap.Error(err))
+			os.Exit(-1)
+		}
+	}
+
+	var downloads *download.Issuer
+	var downloadCounter *download.RedisCounter
+	if conf.DownloadSigningKeyId != "" {
+		signer, err := download.NewSigner(conf.DownloadBaseUrl, conf.DownloadSigningKeyId, conf.DownloadSigningKeys, conf.DownloadLinkExpiry)
+		if err != nil {
+			logger.Error("error on creating download signer", zap.Error(err))
+			os.Exit(-1)
+		}
+
+		downloadCounter, err = download.NewRedisCounter(conf.RedisUrl, conf.DownloadCountWindow)
+		if err != nil {
+			logger.Error("error on creating download counter", zap.Error(err))
+			os.Exit(-1)
+		}
+		downloads = download.NewIssuer(signer, downloadCounter, conf.DownloadMaxCount)
+	}
+
+	// left nil unless enabled, checkouts are then paid within the request
+	var checkoutQueue checkout.Queue
+	var pgCheckoutQueue *checkout.PGQueue
+	var sealer *checkout.Sealer
+	if conf.CheckoutAsync {
+		sealer, err = checkout.NewSealer(conf.CheckoutJobKey)
+		if err != nil {
+			logger.Error("error on creating checkout card sealer", zap.Error(err))
+			os.Exit(-1)
+		}
+
+		pgCheckoutQueue, err = checkout.NewPGQueue(conf.PostgresqlUrl)
+		if err != nil {
+			logger.Error("error on creating pg checkout queue", zap.Error(err))
+			os.Exit(-1)
+		}
+		m.RegisterPool("postgresql_checkout_queue", pgCheckoutQueue.PoolStats)
+		checkoutQueue = pgCheckoutQueue
+	}
+
+	var webhookVerifier *webhook.Verifier
+	var pgWebhookStorage *webhook.PGEventStorage
+	if len(conf.PaymentWebhookSecrets) > 0 {
+		webhookVerifier = webhook.NewVerifier(conf.PaymentWebhookSecrets, conf.PaymentWebhookTolerance)
+
+		pgWebhookStorage, err = webhook.NewPGEventStorage(conf.PostgresqlUrl)
+		if err != nil {
+			logger.Error("error on creating pg webhook event store", zap.Error(err))
+			os.Exit(-1)
+		}
+		m.RegisterPool("postgresql_webhook_event", pgWebhookStorage.PoolStats)
+	}
+
+	handler := server.NewHandler(server.HandlerDeps{
+		Logger:             logger,
+		CartStorage:        redisCartStorage,
+		OrderStorage:       pgOrderStorage,
+		EntitlementStorage: pgEntitlementStorage,
+		PromoStorage:       pgPromoStorage,
+		InvoiceStorage:     pgInvoiceStorage,
+		InvoiceBuilder:     invoiceBuilder,
+		GiftStorage:        pgGiftStorage,
+		GiftValidity:       conf.GiftValidity,
+		WalletStorage:      pgWalletStorage,
+		LedgerStorage:      pgLedgerStorage,
+		ProductClient:      productClient,
+		ExchangeClient:     exchangeClient,
+		PaymentClient:      paymentClient,
+		HealthChecker:      healthChecker,
+		Metrics:            m,
+		RiskEvaluator:      riskEvaluator,
+		Fingerprinter:      fingerprinter,
+		TaxCalculator:      taxCalculator,
+		IPCountryHeader:    conf.TaxIPCountryHeader,
+		Downloads:          downloads,
+		CheckoutQueue:      checkoutQueue,
+		Sealer:             sealer,
+		Broker:             broker,
+		WebhookVerifier:    webhookVerifier,
+		WebhookStorage:     pgWebhookStorage,
+		DisputeStorage:     pgDisputeStorage,
+	})
+
+	var pool *checkout.Pool
+	if pgCheckoutQueue != nil {
+		pool = checkout.NewPool(logger, pgCheckoutQueue, handler.CheckoutProcessor(), conf.CheckoutWorkers, conf.CheckoutJobLease, conf.CheckoutJobAttempts, conf.CheckoutPollInterval)
+		pool.Start()
+	}
+
+	var rateLimiter ratelimit.Limiter
+	switch conf.RateLimitBackend {
+	case "redis":
+		redisLimiter, err := ratelimit.NewRedisLimiter(conf.RedisUrl)
+		if err != nil {
+			logger.Error("error on creating rate limiter", zap.Error(err))
+			os.Exit(-1)
+		}
+		rateLimiter = redisLimiter
+	case "memory":
+		rateLimiter = ratelimit.NewMemoryLimiter()
+	case "none":
+		logger.Warn("rate limiting is disabled")
+	default:
+		logger.Error("unknown rate limit backend", zap.String("backend", conf.RateLimitBackend))
+		os.Exit(-1)
+	}
+
+	srvr := server.NewServer(&handler, conf, rateLimiter)
+
+	// stopped in this order: stop taking requests and drain the in-flight
+	// ones first, then close what they were using. The event streams never
+	// end on their own, so the broker goes first to end them
+	manager := lifecycle.NewManager(logger, conf.ShutdownTimeout)
+	manager.Register("order events broker", func(ctx context.Context) error {
+		return broker.Close()
+	})
+	manager.Register("http server", srvr.Shutdown)
+	if pool != nil {
+		manager.Register("checkout workers", pool.Stop)
+		manager.Register("pg checkout queue", func(ctx context.Context) error {
+			return pgCheckoutQueue.Close()
+		})
+	}
+	manager.Register("redis cart storage", func(ctx context.Context) error {
+		return redisCartStorage.Close()
+	})
+	manager.Register("pg order storage", func(ctx context.Context) error {
+		return pgOrderStorage.Close()
+	})
+	manager.Register("pg entitlement storage", func(ctx context.Context) error {
+		return pgEntitlementStorage.Close()
+	})
+	manager.Register("pg promo storage", func(ctx context.Context) error {
+		return pgPromoStorage.Close()
+	})
+	manager.Register("pg invoice storage", func(ctx context.Context) error {
+		return pgInvoiceStorage.Close()
+	})
+	manager.Register("pg gift storage", func(ctx context.Context) error {
+		return pgGiftStorage.Close()
+	})
+	manager.Register("pg wallet storage", func(ctx context.Context) error {
+		return pgWalletStorage.Close()
+	})
+	manager.Register("pg ledger storage", func(ctx context.Context) error {
+		return pgLedgerStorage.Close()
+	})
+	manager.Register("pg dispute storage", func(ctx context.Context) error {
+		return pgDisputeStorage.Close()
+	})
+	if pgWebhookStorage != nil {
+		manager.Register("pg webhook event storage", func(ctx context.Context) error {
+			return pgWebhookStorage.Close()
+		})
+	}
+	if velocityStore != nil {
+		manager.Register("risk velocity store", func(ctx context.Context) error {
+			return velocityStore.Close()
+		})
+	}
+	if downloadCounter != nil {
+		manager.Register("download counter", func(ctx context.Context) error {
+			return downloadCounter.Close()
+		})
+	}
+	if closer, ok := rateLimiter.(io.Closer); ok {
+		manager.Register("rate limiter", func(ctx context.Context) error {
+			return closer.Close()
+		})
+	}
+	manager.Register("tracer", tracer.Shutdown)
+
+	if err := manager.Run(srvr.Listen); err != nil {
+		logger.Error("error on running service", zap.Error(err))
+		logger.Sync()
+		os.Exit(1)
+	}
+}
+
+func newTracer(conf *config.Config) (telemetry.Tracer, error) {
+	switch conf.TracingBackend {
+	case "elastic":
+		return telemetry.NewElastic(), nil
+	case "otel":
+		exporter, err := telemetry.NewOTelExporter(context.Background(), conf.OTelExporter)
+		if err != nil {
+			return nil, err
+		}
+		return telemetry.NewOTel(conf.ServiceId, exporter), nil
+	case "none":
+		return telemetry.Nop{}, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing backend %q", conf.TracingBackend)
+	}
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"orderservice/pkg/client/payment"
+	"orderservice/pkg/config"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/reconcile"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/repo/wallet"
+	"orderservice/pkg/serviceauth"
+	"orderservice/pkg/telemetry"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// reconcile checks the payments of the orders against the payment service,
+// once over -from/-to or the last RECONCILE_WINDOW, or periodically when
+// RECONCILE_INTERVAL is set.
+func main() {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+
+	from := flag.String("from", "", "start of the window, RFC 3339")
+	to := flag.String("to", "", "end of the window, RFC 3339, now by default")
+	repair := flag.Bool("repair", false, "complete the orders charged but not completed when it is safe, and give back the store credit held by orders never charged")
+	flag.Parse()
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Error("error on during configuration", zap.Error(err))
+		os.Exit(-1)
+	}
+	telemetry.SetDefault(telemetry.Nop{})
+
+	pgOrderStorage, err := order.NewPGOrderStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg store", zap.Error(err))
+		os.Exit(-1)
+	}
+	defer pgOrderStorage.Close()
+
+	pgWalletStorage, err := wallet.NewPGWalletStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg wallet store", zap.Error(err))
+		os.Exit(-1)
+	}
+	defer pgWalletStorage.Close()
+
+	pgReportStorage, err := reconcile.NewPGReportStorage(conf.PostgresqlUrl)
+	if err != nil {
+		logger.Error("error on creating pg report store", zap.Error(err))
+		os.Exit(-1)
+	}
+	defer pgReportStorage.Close()
+
+	var signer serviceauth.Signer
+	if conf.ServiceHMACSigningKey != "" {
+		key, ok := conf.ServiceHMACKeys[conf.ServiceHMACSigningKey]
+		if !ok {
+			logger.Error("signing key is not in SERVICE_HMAC_KEYS", zap.String("key_id", conf.ServiceHMACSigningKey))
+			os.Exit(-1)
+		}
+		signer = serviceauth.NewHMACSigner(conf.ServiceId, conf.ServiceHMACSigningKey, key)
+	}
+	paymentClient := payment.NewPaymentClient(conf.PaymentServiceUrl, signer, metrics.Nop{})
+
+	reconciler := reconcile.NewReconciler(logger, pgOrderStorage, pgWalletStorage, paymentClient, conf.ReconcilePageSize, conf.ReconcileMinAge)
+	run := func(ctx context.Context, from time.Time, to time.Time) error {
+		report, err := reconciler.Run(ctx, from, to, *repair || conf.ReconcileRepair)
+		if err != nil {
+			return err
+		}
+		if err := pgReportStorage.Save(ctx, report); err != nil {
+			return err
+		}
+		if conf.ReconcileCSVDir != "" {
+			if err := writeCSV(conf.ReconcileCSVDir, report); err != nil {
+				return err
+			}
+		}
+
+		logger.Info("reconciliation done", zap.String("run_id", report.Id), zap.Time("from", from), zap.Time("to", to),
+			zap.Int("checked", report.Checked), zap.Int("failed", report.Failed), zap.Int("mismatches", len(report.Mismatches)), zap.Int("repaired", report.Repaired))
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if conf.ReconcileInterval == 0 || *from != "" {
+		start, end, err := window(*from, *to, conf.ReconcileWindow)
+		if err != nil {
+			logger.Error("invalid window", zap.Error(err))
+			os.Exit(-1)
+		}
+		if err := run(ctx, start, end); err != nil {
+			logger.Error("error on reconciling payments", zap.Error(err))
+			logger.Sync()
+			os.Exit(1)
+		}
+		return
+	}
+
+	// as a worker, every run checks the last window again so that the
+	// orders whose payment settles late are seen settled eventually
+	ticker := time.NewTicker(conf.ReconcileInterval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		if err := run(ctx, now.Add(-conf.ReconcileWindow), now); err != nil {
+			logger.Error("error on reconciling payments", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func window(from string, to string, size time.Duration) (time.Time, time.Time, error) {
+	end := time.Now()
+	if to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		end = t
+	}
+
+	start := end.Add(-size)
+	if from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		start = t
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("window ends before it starts")
+	}
+	return start, end, nil
+}
+
+func writeCSV(dir string, report *reconcile.Report) error {
+	name := fmt.Sprintf("reconciliation-%s-%s.csv", report.StartedAt.UTC().Format("20060102T150405Z"), report.Id)
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := reconcile.WriteCSV(f, report); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -6,8 +6,299 @@ CREATE TABLE IF NOT EXISTS orders (
 	payment_id uuid,
 	user_id uuid NOT NULL,
 	total DECIMAL NOT NULL,
+	-- of the prices and totals, the store credit spent included
+	currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
 	items JSONB NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	risk_decision VARCHAR,
+	risk_reasons JSONB,
+	-- keyed fingerprint of the card the risk check assessed, an approved
+	-- order is paid with it only
+	card_hmac VARCHAR,
+	subtotal DECIMAL,
+	discounts JSONB,
+	taxes JSONB,
+	-- customer location evidence the tax country was derived from
+	tax_country VARCHAR(2),
+	billing_country VARCHAR(2),
+	ip_country VARCHAR(2),
+	-- part of the total paid with store credit, the card paid the rest
+	wallet_amount DECIMAL NOT NULL DEFAULT 0,
+	-- card leg as charged by the payment service
+	payment_amount DECIMAL,
+	payment_currency VARCHAR(3),
 	PRIMARY KEY (id)
 );
 
+-- columns added since the table was first created, CREATE TABLE IF NOT
+-- EXISTS leaves an existing table as it is
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'EUR';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS risk_decision VARCHAR;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS risk_reasons JSONB;
+-- card_fingerprint held plain hashes of the card numbers, which can be
+-- reversed; orders held for a review before need another checkout
+ALTER TABLE orders DROP COLUMN IF EXISTS card_fingerprint;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS card_hmac VARCHAR;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal DECIMAL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discounts JSONB;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS taxes JSONB;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_country VARCHAR(2);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS billing_country VARCHAR(2);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS ip_country VARCHAR(2);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS wallet_amount DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_amount DECIMAL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_currency VARCHAR(3);
+
 CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders (user_id);
+CREATE INDEX IF NOT EXISTS orders_user_id_created_at_id_idx ON orders (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS orders_user_id_status_created_at_id_idx ON orders (user_id, status, created_at, id);
+CREATE INDEX IF NOT EXISTS orders_status_created_at_idx ON orders (status, created_at, id);
+
+-- products a user has access to, granted when an order completes and revoked
+-- when it is refunded
+CREATE TABLE IF NOT EXISTS entitlements (
+	user_id uuid NOT NULL,
+	product_id uuid NOT NULL,
+	order_id uuid NOT NULL REFERENCES orders (id),
+	name VARCHAR NOT NULL,
+	purchased_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	revoked_at TIMESTAMPTZ,
+	-- set while the order is disputed
+	suspended_at TIMESTAMPTZ,
+	PRIMARY KEY (user_id, product_id)
+);
+
+ALTER TABLE entitlements ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS entitlements_user_id_purchased_at_product_id_idx ON entitlements (user_id, purchased_at, product_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS entitlements_order_id_idx ON entitlements (order_id);
+
+-- promotions redeemed with a code at checkout; empty product_ids and
+-- authors make every product eligible
+CREATE TABLE IF NOT EXISTS promo_codes (
+	code VARCHAR NOT NULL,
+	description VARCHAR NOT NULL DEFAULT '',
+	kind VARCHAR NOT NULL,
+	value DECIMAL NOT NULL DEFAULT 0,
+	buy_quantity INTEGER NOT NULL DEFAULT 0,
+	free_quantity INTEGER NOT NULL DEFAULT 0,
+	starts_at TIMESTAMPTZ,
+	ends_at TIMESTAMPTZ,
+	max_uses INTEGER,
+	max_uses_per_user INTEGER,
+	product_ids uuid[] NOT NULL DEFAULT '{}',
+	authors VARCHAR[] NOT NULL DEFAULT '{}',
+	PRIMARY KEY (code)
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+	code VARCHAR NOT NULL REFERENCES promo_codes (code),
+	user_id uuid NOT NULL,
+	order_id uuid NOT NULL REFERENCES orders (id),
+	redeemed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (order_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS promo_redemptions_code_user_id_idx ON promo_redemptions (code, user_id);
+
+-- invoice numbers are sequential per year without gaps, last_number is
+-- taken in the transaction completing the order
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+	year INTEGER NOT NULL,
+	last_number INTEGER NOT NULL,
+	PRIMARY KEY (year)
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+	order_id uuid NOT NULL REFERENCES orders (id),
+	year INTEGER NOT NULL,
+	sequence INTEGER NOT NULL,
+	issued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (order_id),
+	UNIQUE (year, sequence)
+);
+
+-- orders bought for someone else; the products go to whoever redeems the
+-- code, which becomes active for valid_for seconds once the order is paid
+CREATE TABLE IF NOT EXISTS gifts (
+	code VARCHAR NOT NULL,
+	order_id uuid NOT NULL REFERENCES orders (id),
+	buyer_id uuid NOT NULL,
+	recipient_email VARCHAR,
+	recipient_user_id uuid,
+	message VARCHAR,
+	state VARCHAR NOT NULL,
+	valid_for BIGINT NOT NULL,
+	expires_at TIMESTAMPTZ,
+	redeemed_by uuid,
+	redeemed_at TIMESTAMPTZ,
+	PRIMARY KEY (code),
+	UNIQUE (order_id)
+);
+
+CREATE INDEX IF NOT EXISTS gifts_state_expires_at_idx ON gifts (state, expires_at);
+
+-- backfill the entitlements from the orders completed before they existed;
+-- the products of a gift belong to whoever redeems it, not to the buyer
+INSERT INTO entitlements (user_id, product_id, order_id, name, purchased_at)
+SELECT DISTINCT ON (user_id, item->>'id') user_id, (item->>'id')::uuid, id, item->>'name', created_at
+FROM orders, jsonb_array_elements(items) AS item
+WHERE status = 'completed' AND NOT EXISTS (SELECT 1 FROM gifts WHERE gifts.order_id = orders.id)
+ORDER BY user_id, item->>'id', created_at
+ON CONFLICT (user_id, product_id) DO NOTHING;
+
+-- store credit ledger of the users. Credits are spent by debits, the ones
+-- expiring first first, through allocations so that a reversed debit goes
+-- back to the credits it came from; remaining is what is left of a credit
+CREATE TABLE IF NOT EXISTS wallet_entries (
+	id uuid DEFAULT uuid_generate_v4(),
+	user_id uuid NOT NULL,
+	kind VARCHAR NOT NULL,
+	amount DECIMAL NOT NULL,
+	remaining DECIMAL NOT NULL DEFAULT 0,
+	order_id uuid REFERENCES orders (id),
+	credit_id uuid REFERENCES wallet_entries (id),
+	reason VARCHAR NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ,
+	reversed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS wallet_entries_user_id_created_at_id_idx ON wallet_entries (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS wallet_entries_user_id_credits_idx ON wallet_entries (user_id) WHERE kind = 'credit' AND remaining > 0;
+CREATE INDEX IF NOT EXISTS wallet_entries_order_id_idx ON wallet_entries (order_id);
+
+CREATE TABLE IF NOT EXISTS wallet_allocations (
+	debit_id uuid NOT NULL REFERENCES wallet_entries (id),
+	credit_id uuid NOT NULL REFERENCES wallet_entries (id),
+	amount DECIMAL NOT NULL,
+	PRIMARY KEY (debit_id, credit_id)
+);
+
+-- double-entry journal of the order financials. Amounts are cents, debits
+-- positive and credits negative, the postings of an entry sum to zero in
+-- each currency. Entries are never changed, a refund reverses a capture
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	id uuid DEFAULT uuid_generate_v4(),
+	order_id uuid NOT NULL REFERENCES orders (id),
+	kind VARCHAR NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_order_id_idx ON ledger_entries (order_id);
+CREATE INDEX IF NOT EXISTS ledger_entries_created_at_idx ON ledger_entries (created_at);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+	entry_id uuid NOT NULL REFERENCES ledger_entries (id),
+	account VARCHAR NOT NULL,
+	currency VARCHAR(3) NOT NULL,
+	amount BIGINT NOT NULL,
+	PRIMARY KEY (entry_id, account, currency)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_postings_account_idx ON ledger_postings (account, currency);
+
+CREATE OR REPLACE FUNCTION ledger_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'the ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_append_only ON ledger_entries;
+CREATE TRIGGER ledger_entries_append_only BEFORE UPDATE OR DELETE ON ledger_entries
+	FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+DROP TRIGGER IF EXISTS ledger_postings_append_only ON ledger_postings;
+CREATE TRIGGER ledger_postings_append_only BEFORE UPDATE OR DELETE ON ledger_postings
+	FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+-- runs of the payment reconciliation and the mismatches they found
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+	id uuid DEFAULT uuid_generate_v4(),
+	window_from TIMESTAMPTZ NOT NULL,
+	window_to TIMESTAMPTZ NOT NULL,
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ NOT NULL,
+	checked INTEGER NOT NULL,
+	failed INTEGER NOT NULL,
+	repaired INTEGER NOT NULL,
+	mismatches INTEGER NOT NULL,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_mismatches (
+	run_id uuid NOT NULL REFERENCES reconciliation_runs (id),
+	order_id uuid NOT NULL REFERENCES orders (id),
+	order_status VARCHAR NOT NULL,
+	kind VARCHAR NOT NULL,
+	payment_id VARCHAR NOT NULL DEFAULT '',
+	detail VARCHAR NOT NULL DEFAULT '',
+	repaired BOOLEAN NOT NULL DEFAULT false,
+	PRIMARY KEY (run_id, order_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS reconciliation_mismatches_order_id_idx ON reconciliation_mismatches (order_id);
+
+-- durable queue of the asynchronous checkouts, one job per order kept as
+-- its checkout status; the card token of the payment service is sealed and
+-- dropped once finished, the card details are never stored
+CREATE TABLE IF NOT EXISTS checkout_jobs (
+	order_id uuid NOT NULL REFERENCES orders (id),
+	user_id uuid NOT NULL,
+	state VARCHAR NOT NULL,
+	reason VARCHAR NOT NULL DEFAULT '',
+	card BYTEA,
+	use_wallet BOOLEAN NOT NULL DEFAULT false,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	available_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	locked_until TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (order_id)
+);
+
+CREATE INDEX IF NOT EXISTS checkout_jobs_state_available_at_idx ON checkout_jobs (state, available_at);
+CREATE INDEX IF NOT EXISTS checkout_jobs_user_id_state_idx ON checkout_jobs (user_id, state);
+
+-- payment events received by the webhook, kept to process each once however
+-- many times it is delivered; outcome is set once processed
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+	event_id VARCHAR NOT NULL,
+	type VARCHAR NOT NULL,
+	payment_id VARCHAR NOT NULL DEFAULT '',
+	order_id VARCHAR NOT NULL DEFAULT '',
+	payload JSONB NOT NULL,
+	deliveries INTEGER NOT NULL DEFAULT 1,
+	locked_until TIMESTAMPTZ,
+	outcome VARCHAR,
+	received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	processed_at TIMESTAMPTZ,
+	PRIMARY KEY (event_id)
+);
+
+CREATE INDEX IF NOT EXISTS payment_webhook_events_order_id_idx ON payment_webhook_events (order_id);
+
+-- disputes of the card holders on the payment of an order; the entitlements
+-- of the order are suspended while a dispute is open, at most one per order
+CREATE TABLE IF NOT EXISTS disputes (
+	id uuid DEFAULT uuid_generate_v4(),
+	order_id uuid NOT NULL REFERENCES orders (id),
+	user_id uuid NOT NULL,
+	payment_id VARCHAR NOT NULL DEFAULT '',
+	state VARCHAR NOT NULL,
+	reason VARCHAR NOT NULL DEFAULT '',
+	amount DECIMAL NOT NULL,
+	currency VARCHAR(3) NOT NULL,
+	evidence TEXT NOT NULL DEFAULT '',
+	opened_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	evidence_submitted_at TIMESTAMPTZ,
+	resolved_at TIMESTAMPTZ,
+	PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS disputes_order_id_open_idx ON disputes (order_id) WHERE state IN ('opened', 'evidence_submitted');
+CREATE INDEX IF NOT EXISTS disputes_order_id_opened_at_idx ON disputes (order_id, opened_at);
+CREATE INDEX IF NOT EXISTS disputes_user_id_idx ON disputes (user_id);
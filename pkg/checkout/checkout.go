@@ -0,0 +1,127 @@
+package checkout
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrInvalidKey = errors.New("checkout job key must be 32 bytes, base64 encoded")
+	ErrInProgress = errors.New("checkout already in progress")
+	// ErrPending is returned by a processor whose payment is settled later,
+	// the job ends as pending.
+	ErrPending = errors.New("checkout pending")
+)
+
+const (
+	// waiting for a worker, or for its next attempt
+	StateQueued = "queued"
+	// taken by a worker
+	StateProcessing = "processing"
+	// the order is paid
+	StateSucceeded = "succeeded"
+	// the order couldn't be paid, Reason tells why
+	StateFailed = "failed"
+	// the payment is settled later, the payment webhook completes or fails
+	// the order; the job is not claimed again
+	StatePending = "pending"
+)
+
+// Card is what the card leg of the payment is charged on: the token the
+// payment service keeps the card under, the card details never reach the
+// queue. It is only kept sealed, and dropped once the job is finished.
+type Card struct {
+	Token string `json:"token"`
+}
+
+// Job pays an order in the background. Attempts counts the times the job
+// was claimed, including the current one.
+type Job struct {
+	OrderId   string
+	UserId    string
+	Card      []byte
+	UseWallet bool
+	Attempts  int
+}
+
+// Status is the progress of the checkout of an order.
+type Status struct {
+	OrderId   string
+	UserId    string
+	State     string
+	Reason    string
+	Attempts  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Finished tells whether the checkout won't move anymore, a pending one
+// still waits for the payment webhook.
+func (s Status) Finished() bool {
+	return s.State == StateSucceeded || s.State == StateFailed
+}
+
+// Sealer encrypts the card of the jobs with AES-GCM, so that card tokens
+// are never stored in clear.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer creates a sealer from a base64 encoded 256 bit key.
+func NewSealer(key string) (*Sealer, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts the card for the order, the sealed card can't be opened
+// for another order.
+func (s Sealer) Seal(orderId string, card Card) ([]byte, error) {
+	plain, err := json.Marshal(card)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plain, []byte(orderId)), nil
+}
+
+func (s Sealer) Open(orderId string, sealed []byte) (*Card, error) {
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, fmt.Errorf("sealed card too short")
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, ciphertext, []byte(orderId))
+	if err != nil {
+		return nil, err
+	}
+
+	card := new(Card)
+	if err := json.Unmarshal(plain, card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
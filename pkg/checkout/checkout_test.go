@@ -0,0 +1,109 @@
+package checkout
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newKey(t *testing.T) string {
+	t.Helper()
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestNewSealer(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want error
+	}{
+		{"256 bit key", newKey(t), nil},
+		{"not base64", "not a key!", ErrInvalidKey},
+		{"128 bit key", base64.StdEncoding.EncodeToString(make([]byte, 16)), ErrInvalidKey},
+		{"empty", "", ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSealer(tt.key)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("NewSealer() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSealerOpen(t *testing.T) {
+	sealer, err := NewSealer(newKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSealer(newKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card := Card{Token: "tok_visa_4242"}
+	sealed, err := sealer.Seal("order-1", card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		sealer  *Sealer
+		orderId string
+		sealed  []byte
+		wantErr bool
+	}{
+		{"same order", sealer, "order-1", sealed, false},
+		{"another order", sealer, "order-2", sealed, true},
+		{"another key", other, "order-1", sealed, true},
+		{"tampered", sealer, "order-1", tampered, true},
+		{"too short", sealer, "order-1", sealed[:4], true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sealer.Open(tt.orderId, tt.sealed)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Open() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() = %v", err)
+			}
+			if *got != card {
+				t.Errorf("Open() = %v, want %v", *got, card)
+			}
+		})
+	}
+}
+
+func TestSealDoesNotRepeat(t *testing.T) {
+	sealer, err := NewSealer(newKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := sealer.Seal("order-1", Card{Token: "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := sealer.Seal("order-1", Card{Token: "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) == string(second) {
+		t.Error("Seal() sealed the same card twice the same way")
+	}
+}
@@ -0,0 +1,207 @@
+package checkout
+
+import (
+	"context"
+	"database/sql"
+	"orderservice/pkg/events"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
+	"time"
+)
+
+type PGQueue struct {
+	db *sql.DB
+}
+
+func NewPGQueue(url string) (*PGQueue, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &PGQueue{
+		db: db,
+	}
+	return q, nil
+}
+
+func (q PGQueue) Close() error {
+	return q.db.Close()
+}
+
+func (q PGQueue) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(q.db.Stats())
+}
+
+func (q PGQueue) Enqueue(ctx context.Context, job Job) error {
+	span, ctx := telemetry.StartSpan(ctx, "Enqueue", "PGQueue")
+	defer span.End()
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the jobs of a user are enqueued one at a time, a second checkout
+	// while one is running would spend the same store credit
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", job.UserId); err != nil {
+		return err
+	}
+	var running bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM checkout_jobs WHERE user_id = $1 AND order_id <> $2 AND state IN ($3, $4))",
+		job.UserId, job.OrderId, StateQueued, StateProcessing).Scan(&running)
+	if err != nil {
+		return err
+	}
+	if running {
+		return ErrInProgress
+	}
+
+	// an approved order paid again after a failed checkout gets a new job
+	res, err := tx.ExecContext(ctx, `INSERT INTO checkout_jobs (order_id, user_id, state, card, use_wallet) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_id) DO UPDATE SET state = EXCLUDED.state, card = EXCLUDED.card, use_wallet = EXCLUDED.use_wallet,
+		reason = '', attempts = 0, available_at = now(), locked_until = NULL, updated_at = now()
+		WHERE checkout_jobs.state = $6`,
+		job.OrderId, job.UserId, StateQueued, job.Card, job.UseWallet, StateFailed)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrInProgress
+	}
+
+	if err := publishState(ctx, tx, job.OrderId, StateQueued, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (q PGQueue) Claim(ctx context.Context, lease time.Duration) (*Job, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Claim", "PGQueue")
+	defer span.End()
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED lets the workers of every instance claim different jobs
+	// without waiting on each other
+	job := new(Job)
+	err = tx.QueryRowContext(ctx, `UPDATE checkout_jobs SET state = $1, attempts = attempts + 1,
+		locked_until = now() + $2 * interval '1 second', updated_at = now()
+		WHERE order_id = (
+			SELECT order_id FROM checkout_jobs
+			WHERE (state = $3 AND available_at <= now()) OR (state = $1 AND locked_until < now())
+			ORDER BY available_at FOR UPDATE SKIP LOCKED LIMIT 1
+		)
+		RETURNING order_id, user_id, card, use_wallet, attempts`,
+		StateProcessing, lease.Seconds(), StateQueued,
+	).Scan(&job.OrderId, &job.UserId, &job.Card, &job.UseWallet, &job.Attempts)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := publishState(ctx, tx, job.OrderId, StateProcessing, ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (q PGQueue) Retry(ctx context.Context, orderId string, delay time.Duration, reason string) error {
+	span, ctx := telemetry.StartSpan(ctx, "Retry", "PGQueue")
+	defer span.End()
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE checkout_jobs SET state = $1, reason = $2, available_at = now() + $3 * interval '1 second',
+		locked_until = NULL, updated_at = now() WHERE order_id = $4`,
+		StateQueued, reason, delay.Seconds(), orderId)
+	if err != nil {
+		return err
+	}
+
+	if err := publishState(ctx, tx, orderId, StateQueued, reason); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (q PGQueue) Finish(ctx context.Context, orderId string, state string, reason string) error {
+	span, ctx := telemetry.StartSpan(ctx, "Finish", "PGQueue")
+	defer span.End()
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE checkout_jobs SET state = $1, reason = $2, card = NULL, locked_until = NULL, updated_at = now()
+		WHERE order_id = $3`, state, reason, orderId)
+	if err != nil {
+		return err
+	}
+
+	if err := publishState(ctx, tx, orderId, state, reason); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (q PGQueue) Status(ctx context.Context, orderId string) (*Status, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Status", "PGQueue")
+	defer span.End()
+
+	status := new(Status)
+	err := q.db.QueryRowContext(ctx, "SELECT order_id, user_id, state, reason, attempts, created_at, updated_at FROM checkout_jobs WHERE order_id = $1", orderId).
+		Scan(&status.OrderId, &status.UserId, &status.State, &status.Reason, &status.Attempts, &status.CreatedAt, &status.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func (q PGQueue) Pending(ctx context.Context, userId string) (*Status, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Pending", "PGQueue")
+	defer span.End()
+
+	status := new(Status)
+	err := q.db.QueryRowContext(ctx, `SELECT order_id, user_id, state, reason, attempts, created_at, updated_at FROM checkout_jobs
+		WHERE user_id = $1 AND state IN ($2, $3) ORDER BY created_at LIMIT 1`, userId, StateQueued, StateProcessing).
+		Scan(&status.OrderId, &status.UserId, &status.State, &status.Reason, &status.Attempts, &status.CreatedAt, &status.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func publishState(ctx context.Context, tx *sql.Tx, orderId string, state string, reason string) error {
+	return events.Publish(ctx, tx, events.Event{OrderId: orderId, Type: events.TypeCheckout, State: state, Reason: reason})
+}
@@ -0,0 +1,163 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Processor pays the order of a job.
+type Processor interface {
+	// Process returns a Failure when the order can't be paid and ErrPending
+	// when its payment is settled later, other errors are retried.
+	Process(ctx context.Context, job *Job) error
+	// Abandon undoes what the attempts of a job that won't be retried
+	// anymore left behind.
+	Abandon(ctx context.Context, job *Job)
+}
+
+// Failure ends a job as failed without retrying it.
+type Failure struct {
+	Reason string
+}
+
+func (f Failure) Error() string {
+	return f.Reason
+}
+
+func Fail(reason string) error {
+	return Failure{Reason: reason}
+}
+
+// Pool runs workers taking the jobs from the queue. A job whose attempts
+// all failed is abandoned and finished as failed.
+type Pool struct {
+	logger    *zap.Logger
+	queue     Queue
+	processor Processor
+	workers   int
+	lease     time.Duration
+	attempts  int
+	poll      time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewPool(logger *zap.Logger, queue Queue, processor Processor, workers int, lease time.Duration, attempts int, poll time.Duration) *Pool {
+	return &Pool{
+		logger:    logger,
+		queue:     queue,
+		processor: processor,
+		workers:   workers,
+		lease:     lease,
+		attempts:  attempts,
+		poll:      poll,
+		stop:      make(chan struct{}),
+	}
+}
+
+func (p *Pool) Start() {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+}
+
+// Stop stops claiming jobs and waits for the ones being processed. A job
+// still running when ctx is done is claimed again once its lease ran out.
+func (p *Pool) Stop(ctx context.Context) error {
+	close(p.stop)
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		job, err := p.queue.Claim(context.Background(), p.lease)
+		if err != nil {
+			p.logger.Error("error on claiming checkout job", zap.Error(err))
+		}
+		if job == nil {
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(p.poll):
+			}
+			continue
+		}
+
+		p.run(job)
+	}
+}
+
+func (p *Pool) run(job *Job) {
+	// the job must not outlive its lease, another worker would take it
+	ctx, cancel := context.WithTimeout(context.Background(), p.lease)
+	defer cancel()
+
+	logger := p.logger.With(zap.String("order_id", job.OrderId), zap.Int("attempt", job.Attempts))
+
+	err := p.processor.Process(ctx, job)
+	if err == nil {
+		if err := p.queue.Finish(ctx, job.OrderId, StateSucceeded, ""); err != nil {
+			logger.Error("error on finishing checkout job", zap.Error(err))
+		}
+		return
+	}
+
+	if errors.Is(err, ErrPending) {
+		if err := p.queue.Finish(ctx, job.OrderId, StatePending, ""); err != nil {
+			logger.Error("error on finishing checkout job", zap.Error(err))
+		}
+		return
+	}
+
+	var failure Failure
+	if errors.As(err, &failure) {
+		if err := p.queue.Finish(ctx, job.OrderId, StateFailed, failure.Reason); err != nil {
+			logger.Error("error on finishing checkout job", zap.Error(err))
+		}
+		return
+	}
+
+	logger.Warn("checkout job attempt failed", zap.Error(err))
+	if job.Attempts < p.attempts {
+		if err := p.queue.Retry(ctx, job.OrderId, p.backoff(job.Attempts), err.Error()); err != nil {
+			logger.Error("error on retrying checkout job", zap.Error(err))
+		}
+		return
+	}
+
+	p.processor.Abandon(ctx, job)
+	if err := p.queue.Finish(ctx, job.OrderId, StateFailed, err.Error()); err != nil {
+		logger.Error("error on finishing checkout job", zap.Error(err))
+	}
+}
+
+// backoff doubles the delay after each attempt, from one second
+func (p *Pool) backoff(attempts int) time.Duration {
+	return time.Second << (attempts - 1)
+}
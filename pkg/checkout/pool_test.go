@@ -0,0 +1,92 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recordingQueue keeps what the pool did to the job it ran.
+type recordingQueue struct {
+	state  string
+	reason string
+	delay  time.Duration
+}
+
+func (q *recordingQueue) Enqueue(ctx context.Context, job Job) error {
+	return nil
+}
+
+func (q *recordingQueue) Claim(ctx context.Context, lease time.Duration) (*Job, error) {
+	return nil, nil
+}
+
+func (q *recordingQueue) Retry(ctx context.Context, orderId string, delay time.Duration, reason string) error {
+	q.state, q.reason, q.delay = StateQueued, reason, delay
+	return nil
+}
+
+func (q *recordingQueue) Finish(ctx context.Context, orderId string, state string, reason string) error {
+	q.state, q.reason = state, reason
+	return nil
+}
+
+func (q *recordingQueue) Status(ctx context.Context, orderId string) (*Status, error) {
+	return nil, nil
+}
+
+func (q *recordingQueue) Pending(ctx context.Context, userId string) (*Status, error) {
+	return nil, nil
+}
+
+type stubProcessor struct {
+	err       error
+	abandoned bool
+}
+
+func (p *stubProcessor) Process(ctx context.Context, job *Job) error {
+	return p.err
+}
+
+func (p *stubProcessor) Abandon(ctx context.Context, job *Job) {
+	p.abandoned = true
+}
+
+func TestPoolRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		attempts      int
+		wantState     string
+		wantReason    string
+		wantDelay     time.Duration
+		wantAbandoned bool
+	}{
+		{"paid", nil, 1, StateSucceeded, "", 0, false},
+		{"payment settled later", ErrPending, 1, StatePending, "", 0, false},
+		{"failed", Fail("payment declined"), 1, StateFailed, "payment declined", 0, false},
+		{"first attempt errored", errors.New("timeout"), 1, StateQueued, "timeout", time.Second, false},
+		{"second attempt errored", errors.New("timeout"), 2, StateQueued, "timeout", 2 * time.Second, false},
+		{"last attempt errored", errors.New("timeout"), 3, StateFailed, "timeout", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &recordingQueue{}
+			processor := &stubProcessor{err: tt.err}
+			pool := NewPool(zap.NewNop(), queue, processor, 1, time.Minute, 3, time.Second)
+
+			pool.run(&Job{OrderId: "order-1", Attempts: tt.attempts})
+
+			if queue.state != tt.wantState || queue.reason != tt.wantReason || queue.delay != tt.wantDelay {
+				t.Errorf("job left %q %q after %v, want %q %q after %v", queue.state, queue.reason, queue.delay, tt.wantState, tt.wantReason, tt.wantDelay)
+			}
+			if processor.abandoned != tt.wantAbandoned {
+				t.Errorf("abandoned = %v, want %v", processor.abandoned, tt.wantAbandoned)
+			}
+		})
+	}
+}
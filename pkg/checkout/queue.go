@@ -0,0 +1,28 @@
+package checkout
+
+import (
+	"context"
+	"time"
+)
+
+// Queue is the durable queue of the checkout jobs, one per order.
+type Queue interface {
+	// Enqueue adds a job, replacing the finished job of the order if any.
+	// It fails with ErrInProgress while the order, or another order of the
+	// user, has a job queued or being processed.
+	Enqueue(ctx context.Context, job Job) error
+	// Claim takes the next job for lease, or returns nil when there is none.
+	// A job whose lease ran out, its worker being gone, is claimed again.
+	Claim(ctx context.Context, lease time.Duration) (*Job, error)
+	// Retry puts the job back in the queue for another attempt after delay.
+	Retry(ctx context.Context, orderId string, delay time.Duration, reason string) error
+	// Finish ends the job as succeeded, failed or pending and drops its
+	// card.
+	Finish(ctx context.Context, orderId string, state string, reason string) error
+	// Status returns the progress of the checkout of the order, or nil when
+	// it was not checked out asynchronously.
+	Status(ctx context.Context, orderId string) (*Status, error)
+	// Pending returns the progress of the job of the user queued or being
+	// processed, or nil when there is none.
+	Pending(ctx context.Context, userId string) (*Status, error)
+}
@@ -5,27 +5,41 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/serviceauth"
+	"orderservice/pkg/telemetry"
+	"time"
 
-	"go.elastic.co/apm/v2"
+	"go.uber.org/zap"
 )
 
 type ExchangeClient struct {
 	baseUrl string
+	signer  serviceauth.Signer
+	metrics metrics.Metrics
 }
 
-func NewExchangeClient(baseUrl string) *ExchangeClient {
+func NewExchangeClient(baseUrl string, signer serviceauth.Signer, metrics metrics.Metrics) *ExchangeClient {
 	return &ExchangeClient{
 		baseUrl: baseUrl,
+		signer:  signer,
+		metrics: metrics,
 	}
 }
 
 func (p ExchangeClient) GetTotal(ctx context.Context, from, to string, amount float32) (*ExchangeResult, error) {
-	span, _ := apm.StartSpan(ctx, "GetTotal", "ExchangeClient")
+	span, ctx := telemetry.StartSpan(ctx, "GetTotal", "ExchangeClient")
 	defer span.End()
 
 	url := fmt.Sprintf("%s/_private/api/v1/%s/%s/%.2f", p.baseUrl, from, to, amount)
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do("GetTotal", req)
 	if err != nil {
 		return nil, err
 	}
@@ -43,3 +57,48 @@ func (p ExchangeClient) GetTotal(ctx context.Context, from, to string, amount fl
 
 	return &exchangeResult, nil
 }
+
+func (p ExchangeClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseUrl+"/_health", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.do("Ping", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p ExchangeClient) do(operation string, req *http.Request) (*http.Response, error) {
+	if p.signer != nil {
+		if err := p.signer.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+	telemetry.Inject(req)
+	if requestId := logging.RequestIdFromContext(req.Context()); requestId != "" {
+		req.Header.Set(logging.RequestIdHeader, requestId)
+	}
+
+	logger := logging.FromContext(req.Context()).With(zap.String("client", "exchange"), zap.String("operation", operation))
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	latency := time.Since(start)
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	p.metrics.ObserveClientRequest("exchange", operation, latency, failed)
+
+	if err != nil {
+		logger.Warn("downstream request failed", zap.Duration("latency", latency), zap.Error(err))
+		return nil, err
+	}
+	logger.Debug("downstream request done", zap.Int("status", resp.StatusCode), zap.Duration("latency", latency))
+	return resp, nil
+}
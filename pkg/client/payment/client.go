@@ -4,24 +4,35 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/serviceauth"
+	"orderservice/pkg/telemetry"
+	"time"
 
-	"go.elastic.co/apm/v2"
+	"go.uber.org/zap"
 )
 
 type PaymentClient struct {
 	baseUrl string
+	signer  serviceauth.Signer
+	metrics metrics.Metrics
 }
 
-func NewPaymentClient(baseUrl string) *PaymentClient {
+func NewPaymentClient(baseUrl string, signer serviceauth.Signer, metrics metrics.Metrics) *PaymentClient {
 	return &PaymentClient{
 		baseUrl: baseUrl,
+		signer:  signer,
+		metrics: metrics,
 	}
 }
 
 func (p PaymentClient) MakePayment(ctx context.Context, paymentReq PaymentRequest) (*PaymentResponse, error) {
-	span, _ := apm.StartSpan(ctx, "MakePayment", "PaymentClient")
+	span, ctx := telemetry.StartSpan(ctx, "MakePayment", "PaymentClient")
 	defer span.End()
 
 	url := fmt.Sprintf("%s/_private/api/v1/payment", p.baseUrl)
@@ -31,12 +42,25 @@ func (p PaymentClient) MakePayment(ctx context.Context, paymentReq PaymentReques
 		return nil, err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(payload))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.do("MakePayment", req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusPaymentRequired {
+		return nil, ErrPaymentDeclined
+	}
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, ErrPaymentPending
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("request failed with status code %d", resp.StatusCode)
 	}
@@ -49,3 +73,191 @@ func (p PaymentClient) MakePayment(ctx context.Context, paymentReq PaymentReques
 
 	return &paymentRes, nil
 }
+
+// TokenizeCard stores the card in the payment service, the token it
+// returns charges the card without its details being kept anywhere else.
+func (p PaymentClient) TokenizeCard(ctx context.Context, card Card) (string, error) {
+	span, ctx := telemetry.StartSpan(ctx, "TokenizeCard", "PaymentClient")
+	defer span.End()
+
+	url := fmt.Sprintf("%s/_private/api/v1/payment/cards", p.baseUrl)
+
+	payload, err := json.Marshal(card)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.do("TokenizeCard", req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPaymentRequired {
+		return "", ErrPaymentDeclined
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	}
+
+	var tokenRes CardTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenRes); err != nil {
+		return "", err
+	}
+	if tokenRes.Token == "" {
+		return "", errors.New("payment service returned no card token")
+	}
+
+	return tokenRes.Token, nil
+}
+
+// RefundPayment pays a captured payment back to the card, failing with
+// ErrPaymentNotFound when the payment service doesn't know it. Refunding a
+// payment refunded already succeeds.
+func (p PaymentClient) RefundPayment(ctx context.Context, id string) error {
+	span, ctx := telemetry.StartSpan(ctx, "RefundPayment", "PaymentClient")
+	defer span.End()
+
+	url := fmt.Sprintf("%s/_private/api/v1/payment/%s/refund", p.baseUrl, url.PathEscape(id))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.do("RefundPayment", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrPaymentNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GetPayment looks a payment up by its id, failing with ErrPaymentNotFound
+// when the payment service doesn't know it.
+func (p PaymentClient) GetPayment(ctx context.Context, id string) (*Payment, error) {
+	span, ctx := telemetry.StartSpan(ctx, "GetPayment", "PaymentClient")
+	defer span.End()
+
+	url := fmt.Sprintf("%s/_private/api/v1/payment/%s", p.baseUrl, url.PathEscape(id))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do("GetPayment", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrPaymentNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	}
+
+	var payment Payment
+	err = json.NewDecoder(resp.Body).Decode(&payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// FindPayments looks up the payments made for an order, whatever their
+// status.
+func (p PaymentClient) FindPayments(ctx context.Context, orderId string) ([]Payment, error) {
+	span, ctx := telemetry.StartSpan(ctx, "FindPayments", "PaymentClient")
+	defer span.End()
+
+	url := fmt.Sprintf("%s/_private/api/v1/payment?order_id=%s", p.baseUrl, url.QueryEscape(orderId))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do("FindPayments", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	}
+
+	payments := []Payment{}
+	err = json.NewDecoder(resp.Body).Decode(&payments)
+	if err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+func (p PaymentClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseUrl+"/_health", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.do("Ping", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p PaymentClient) do(operation string, req *http.Request) (*http.Response, error) {
+	if p.signer != nil {
+		if err := p.signer.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+	telemetry.Inject(req)
+	if requestId := logging.RequestIdFromContext(req.Context()); requestId != "" {
+		req.Header.Set(logging.RequestIdHeader, requestId)
+	}
+
+	logger := logging.FromContext(req.Context()).With(zap.String("client", "payment"), zap.String("operation", operation))
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	latency := time.Since(start)
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	p.metrics.ObserveClientRequest("payment", operation, latency, failed)
+
+	if err != nil {
+		logger.Warn("downstream request failed", zap.Duration("latency", latency), zap.Error(err))
+		return nil, err
+	}
+	logger.Debug("downstream request done", zap.Int("status", resp.StatusCode), zap.Duration("latency", latency))
+	return resp, nil
+}
@@ -1,13 +1,62 @@
 package payment
 
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrPaymentDeclined is returned when the payment service refuses the card.
+	ErrPaymentDeclined = errors.New("payment declined")
+	// ErrPaymentNotFound is returned when the payment service doesn't know
+	// the payment.
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrPaymentPending is returned when the payment service accepted the
+	// payment but settles it later, its webhook tells how it ended.
+	ErrPaymentPending = errors.New("payment pending")
+)
+
+const (
+	// accepted, waiting for the bank
+	StatusPending  = "pending"
+	StatusCaptured = "captured"
+	StatusFailed   = "failed"
+	StatusRefunded = "refunded"
+	// taken back by the bank after a dispute of the card holder
+	StatusChargedBack = "charged_back"
+)
+
+// Card is what a payment is charged on, either the card details or the
+// token the payment service keeps them under.
+type Card struct {
+	Number  string `json:"card_number,omitempty"`
+	ExpDate string `json:"exp_date,omitempty"`
+	CVV     string `json:"cvv,omitempty"`
+	Token   string `json:"card_token,omitempty"`
+}
+
 type PaymentRequest struct {
-	Amount     float64 `json:"amount"`
-	Currency   string  `json:"currency"`
-	CardNumber string  `json:"card_number"`
-	ExpDate    string  `json:"exp_date"`
-	CVV        string  `json:"cvv"`
+	// the order paid, payments are looked up by it
+	OrderId  string  `json:"order_id"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Card
+}
+
+type CardTokenResponse struct {
+	Token string `json:"token"`
 }
 
 type PaymentResponse struct {
 	Id string `json:"id"`
 }
+
+// Payment is a payment as recorded by the payment service.
+type Payment struct {
+	Id        string    `json:"id"`
+	OrderId   string    `json:"order_id"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
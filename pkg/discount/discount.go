@@ -0,0 +1,169 @@
+package discount
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	// Value is the percentage taken off the eligible items
+	Percentage Kind = "percentage"
+	// Value is the amount taken off the eligible items, at most their sum
+	Fixed Kind = "fixed"
+	// of every BuyQuantity+FreeQuantity eligible items the FreeQuantity
+	// cheapest ones are free
+	BuyXGetY Kind = "buy_x_get_y"
+)
+
+var (
+	ErrCodeNotFound      = errors.New("promo code not found")
+	ErrCodeNotActive     = errors.New("promo code is not active")
+	ErrUsageLimitReached = errors.New("promo code usage limit reached")
+	ErrNotEligible       = errors.New("no item in the cart is eligible for the promo code")
+	ErrNotEnoughItems    = errors.New("not enough eligible items for the promo code")
+	ErrUnsupportedKind   = errors.New("unsupported promo code kind")
+)
+
+// PromoCode is a promotion redeemed with its code. Empty ProductIds and
+// Authors make every item eligible, otherwise an item is eligible when it
+// matches either of them.
+type PromoCode struct {
+	Code           string
+	Description    string
+	Kind           Kind
+	Value          float32
+	BuyQuantity    int
+	FreeQuantity   int
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	MaxUses        *int
+	MaxUsesPerUser *int
+	ProductIds     []string
+	Authors        []string
+}
+
+type Item struct {
+	Id     string
+	Author string
+	Price  float32
+}
+
+// Line is a discount applied to an order.
+type Line struct {
+	Code        string
+	Description string
+	Amount      float32
+}
+
+// NormalizeCode makes codes case insensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p PromoCode) Active(now time.Time) bool {
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// Apply computes the discount of the code on items. Usage limits are
+// checked by the storage, as they depend on the other redemptions.
+func (p PromoCode) Apply(items []Item, now time.Time) (*Line, error) {
+	if !p.Active(now) {
+		return nil, ErrCodeNotActive
+	}
+
+	eligible := []Item{}
+	for _, item := range items {
+		if p.eligible(item) {
+			eligible = append(eligible, item)
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, ErrNotEligible
+	}
+
+	var sum float32
+	for _, item := range eligible {
+		sum += item.Price
+	}
+
+	var amount float32
+	switch p.Kind {
+	case Percentage:
+		amount = sum * p.Value / 100
+	case Fixed:
+		amount = p.Value
+	case BuyXGetY:
+		group := p.BuyQuantity + p.FreeQuantity
+		if p.FreeQuantity < 1 || len(eligible) < group {
+			return nil, ErrNotEnoughItems
+		}
+
+		sort.SliceStable(eligible, func(i, j int) bool {
+			return eligible[i].Price > eligible[j].Price
+		})
+		for i, item := range eligible {
+			if i%group >= p.BuyQuantity {
+				amount += item.Price
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKind, p.Kind)
+	}
+
+	amount = roundCents(amount)
+	if amount > sum {
+		amount = sum
+	}
+
+	line := &Line{
+		Code:        p.Code,
+		Description: p.Description,
+		Amount:      amount,
+	}
+	return line, nil
+}
+
+func (p PromoCode) eligible(item Item) bool {
+	if len(p.ProductIds) == 0 && len(p.Authors) == 0 {
+		return true
+	}
+
+	for _, id := range p.ProductIds {
+		if strings.EqualFold(id, item.Id) {
+			return true
+		}
+	}
+	for _, author := range p.Authors {
+		if item.Author != "" && strings.EqualFold(author, item.Author) {
+			return true
+		}
+	}
+	return false
+}
+
+func roundCents(amount float32) float32 {
+	return float32(math.Round(float64(amount)*100) / 100)
+}
+
+// IsRejected tells whether err means the code can't be applied, as opposed
+// to a failure looking it up.
+func IsRejected(err error) bool {
+	for _, target := range []error{ErrCodeNotFound, ErrCodeNotActive, ErrUsageLimitReached, ErrNotEligible, ErrNotEnoughItems, ErrUnsupportedKind} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
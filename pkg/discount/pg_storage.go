@@ -0,0 +1,158 @@
+package discount
+
+import (
+	"context"
+	"database/sql"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+type PGPromoStorage struct {
+	db *sql.DB
+}
+
+func NewPGPromoStorage(url string) (*PGPromoStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGPromoStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGPromoStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGPromoStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGPromoStorage) Get(ctx context.Context, code string) (*PromoCode, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Get", "PGPromoStorage")
+	defer span.End()
+
+	query := `SELECT code, description, kind, value, buy_quantity, free_quantity, starts_at, ends_at,
+		max_uses, max_uses_per_user, product_ids, authors FROM promo_codes WHERE code = $1`
+	row := s.db.QueryRowContext(ctx, query, code)
+
+	p := new(PromoCode)
+	var kind string
+	var value float64
+	var startsAt, endsAt sql.NullTime
+	var maxUses, maxUsesPerUser sql.NullInt64
+	var productIds, authors pq.StringArray
+	err := row.Scan(&p.Code, &p.Description, &kind, &value, &p.BuyQuantity, &p.FreeQuantity, &startsAt, &endsAt,
+		&maxUses, &maxUsesPerUser, &productIds, &authors)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	p.Kind = Kind(kind)
+	p.Value = float32(value)
+	p.ProductIds = productIds
+	p.Authors = authors
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	if maxUses.Valid {
+		uses := int(maxUses.Int64)
+		p.MaxUses = &uses
+	}
+	if maxUsesPerUser.Valid {
+		uses := int(maxUsesPerUser.Int64)
+		p.MaxUsesPerUser = &uses
+	}
+
+	return p, nil
+}
+
+func (s PGPromoStorage) Uses(ctx context.Context, code string, userId string) (int, int, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Uses", "PGPromoStorage")
+	defer span.End()
+
+	return uses(ctx, s.db, code, userId)
+}
+
+func (s PGPromoStorage) Redeem(ctx context.Context, code string, userId string, orderId string) error {
+	span, ctx := telemetry.StartSpan(ctx, "Redeem", "PGPromoStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the code row is locked so concurrent checkouts can't both take the
+	// last use
+	p := new(PromoCode)
+	var maxUses, maxUsesPerUser sql.NullInt64
+	err = tx.QueryRowContext(ctx, "SELECT max_uses, max_uses_per_user FROM promo_codes WHERE code = $1 FOR UPDATE", code).Scan(&maxUses, &maxUsesPerUser)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrCodeNotFound
+		}
+		return err
+	}
+	if maxUses.Valid {
+		limit := int(maxUses.Int64)
+		p.MaxUses = &limit
+	}
+	if maxUsesPerUser.Valid {
+		limit := int(maxUsesPerUser.Int64)
+		p.MaxUsesPerUser = &limit
+	}
+
+	total, byUser, err := uses(ctx, tx, code, userId)
+	if err != nil {
+		return err
+	}
+	if err := CheckLimits(p, total, byUser); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO promo_redemptions (code, user_id, order_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", code, userId, orderId)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug("promo code redeemed", zap.String("code", code), zap.String("order_id", orderId))
+	return nil
+}
+
+func (s PGPromoStorage) Release(ctx context.Context, orderId string) error {
+	span, ctx := telemetry.StartSpan(ctx, "Release", "PGPromoStorage")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM promo_redemptions WHERE order_id = $1", orderId)
+	return err
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func uses(ctx context.Context, q querier, code string, userId string) (int, int, error) {
+	var total, byUser int
+	query := "SELECT count(*), count(*) FILTER (WHERE user_id = $2) FROM promo_redemptions WHERE code = $1"
+	err := q.QueryRowContext(ctx, query, code, userId).Scan(&total, &byUser)
+	return total, byUser, err
+}
@@ -0,0 +1,27 @@
+package discount
+
+import "context"
+
+type PromoStorage interface {
+	// Get returns the promo code or nil when there is no such code.
+	Get(ctx context.Context, code string) (*PromoCode, error)
+	// Uses counts the redemptions of the code in total and by the user.
+	Uses(ctx context.Context, code string, userId string) (int, int, error)
+	// Redeem records the use of the code by the order, or fails with
+	// ErrUsageLimitReached when a limit of the code would be exceeded.
+	Redeem(ctx context.Context, code string, userId string, orderId string) error
+	// Release drops the redemptions of an order that was not paid, so they
+	// don't count against the limits.
+	Release(ctx context.Context, orderId string) error
+}
+
+// CheckLimits tells whether the code can be used once more given its uses.
+func CheckLimits(p *PromoCode, total int, byUser int) error {
+	if p.MaxUses != nil && total >= *p.MaxUses {
+		return ErrUsageLimitReached
+	}
+	if p.MaxUsesPerUser != nil && byUser >= *p.MaxUsesPerUser {
+		return ErrUsageLimitReached
+	}
+	return nil
+}
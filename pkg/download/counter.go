@@ -0,0 +1,56 @@
+package download
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Counter counts the download links issued to a user for a product.
+type Counter interface {
+	Increment(ctx context.Context, userId string, productId string) (int, error)
+}
+
+// incrementWithin starts the window with the first link, the count is
+// dropped once it is over.
+var incrementWithin = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// RedisCounter counts the links issued within window, the count of a user
+// and product starts over when the window of its first link is over.
+type RedisCounter struct {
+	redisClient *redis.Client
+	window      time.Duration
+}
+
+func NewRedisCounter(url string, window time.Duration) (*RedisCounter, error) {
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &RedisCounter{
+		redisClient: redis.NewClient(opt),
+		window:      window,
+	}
+	return counter, nil
+}
+
+func (r RedisCounter) Increment(ctx context.Context, userId string, productId string) (int, error) {
+	count, err := incrementWithin.Run(ctx, r.redisClient, []string{counterKey(userId, productId)}, r.window.Milliseconds()).Int()
+	return count, err
+}
+
+func (r RedisCounter) Close() error {
+	return r.redisClient.Close()
+}
+
+func counterKey(userId string, productId string) string {
+	return "download:" + userId + ":" + productId
+}
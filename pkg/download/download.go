@@ -0,0 +1,67 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrLimitReached = errors.New("download limit reached")
+
+type Link struct {
+	Url       string
+	ExpiresAt time.Time
+	// Remaining is the number of links the user can still get for the
+	// product in the current window, nil when downloads are not limited.
+	Remaining *int
+}
+
+// Issuer hands out signed links, at most maxDownloads per user and product
+// when maxDownloads is positive. The quota counts the links issued, not the
+// files fetched with them: a link can be used as long as it is valid.
+type Issuer struct {
+	signer       *Signer
+	counter      Counter
+	maxDownloads int
+}
+
+func NewIssuer(signer *Signer, counter Counter, maxDownloads int) *Issuer {
+	return &Issuer{
+		signer:       signer,
+		counter:      counter,
+		maxDownloads: maxDownloads,
+	}
+}
+
+func (i Issuer) Issue(ctx context.Context, userId string, productId string, path string, filename string) (*Link, error) {
+	url, expiresAt, err := i.signer.Sign(path, filename, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	link := &Link{
+		Url:       url,
+		ExpiresAt: expiresAt,
+	}
+
+	// counted once the link is signed, a link that couldn't be made doesn't
+	// use up the quota
+	if i.maxDownloads > 0 {
+		count, err := i.counter.Increment(ctx, userId, productId)
+		if err != nil {
+			return nil, err
+		}
+		if count > i.maxDownloads {
+			return nil, ErrLimitReached
+		}
+		remaining := i.maxDownloads - count
+		link.Remaining = &remaining
+	}
+
+	return link, nil
+}
+
+// ObjectPath is where the uploaded file of a product is kept in the object
+// storage.
+func ObjectPath(uploadId string, objectId string) string {
+	return uploadId + "/" + objectId
+}
@@ -0,0 +1,109 @@
+package download
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidSignature = errors.New("invalid download signature")
+
+const (
+	ParamExpires   = "expires"
+	ParamKeyId     = "key_id"
+	ParamFilename  = "filename"
+	ParamSignature = "signature"
+)
+
+// Signer signs object storage urls so they can be fetched without further
+// authentication until they expire. Keys are rotated the same way as the
+// service keys: the new key is added to every verifier first, then keyId is
+// switched to it, and the old key is dropped once the links signed with it
+// expired.
+type Signer struct {
+	baseUrl string
+	keyId   string
+	keys    map[string][]byte
+	expiry  time.Duration
+}
+
+func NewSigner(baseUrl string, keyId string, keys map[string]string, expiry time.Duration) (*Signer, error) {
+	byteKeys := map[string][]byte{}
+	for id, key := range keys {
+		byteKeys[id] = []byte(key)
+	}
+
+	if _, ok := byteKeys[keyId]; !ok {
+		return nil, fmt.Errorf("download signing key %q is not configured", keyId)
+	}
+
+	s := &Signer{
+		baseUrl: baseUrl,
+		keyId:   keyId,
+		keys:    byteKeys,
+		expiry:  expiry,
+	}
+	return s, nil
+}
+
+// Sign returns the url of the object at path valid until the returned time.
+func (s Signer) Sign(path string, filename string, now time.Time) (string, time.Time, error) {
+	u, err := url.Parse(s.baseUrl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	u = u.JoinPath(path)
+
+	expiresAt := now.Add(s.expiry).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set(ParamExpires, expires)
+	query.Set(ParamKeyId, s.keyId)
+	if filename != "" {
+		query.Set(ParamFilename, filename)
+	}
+	query.Set(ParamSignature, signature(s.keys[s.keyId], u.EscapedPath(), expires, s.keyId, filename))
+	u.RawQuery = query.Encode()
+
+	return u.String(), expiresAt, nil
+}
+
+// Verify checks a url produced by Sign with any of the known keys, for the
+// object storage or download proxy serving the files.
+func (s Signer) Verify(u *url.URL, now time.Time) error {
+	query := u.Query()
+	expires := query.Get(ParamExpires)
+	keyId := query.Get(ParamKeyId)
+	sig := query.Get(ParamSignature)
+
+	key, ok := s.keys[keyId]
+	if !ok || sig == "" {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.After(time.Unix(unix, 0)) {
+		return ErrInvalidSignature
+	}
+
+	expected := signature(key, u.EscapedPath(), expires, keyId, query.Get(ParamFilename))
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// signature covers the object path, expiry, key and the filename the
+// object is served as.
+func signature(key []byte, path string, expires string, keyId string, filename string) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", path, expires, keyId, filename)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// subscriberBuffer is how many events a slow subscriber can lag behind
+// before its events are dropped.
+const subscriberBuffer = 16
+
+// Broker listens to the events of every instance and hands them to the
+// subscribers of their order in this instance.
+type Broker struct {
+	logger   *zap.Logger
+	listener *pq.Listener
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+	closed      bool
+}
+
+// NewPGBroker creates a broker listening in the background, it doesn't
+// wait for postgres to be up.
+func NewPGBroker(url string, logger *zap.Logger) *Broker {
+	listener := pq.NewListener(url, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn("order events listener", zap.Error(err))
+		}
+	})
+
+	b := &Broker{
+		logger:      logger,
+		listener:    listener,
+		subscribers: map[string]map[chan Event]struct{}{},
+	}
+	go b.run()
+	return b
+}
+
+// Subscribe returns the events of the order from now on, until cancel is
+// called or the broker is closed, which closes the channel.
+func (b *Broker) Subscribe(orderId string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[orderId] == nil {
+		b.subscribers[orderId] = map[chan Event]struct{}{}
+	}
+	b.subscribers[orderId][ch] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[orderId][ch]; !ok {
+			return
+		}
+		delete(b.subscribers[orderId], ch)
+		if len(b.subscribers[orderId]) == 0 {
+			delete(b.subscribers, orderId)
+		}
+		close(ch)
+	}
+	return ch, cancel
+}
+
+// Close stops listening and closes the channels of the subscribers.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	for _, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+	}
+	b.subscribers = map[string]map[chan Event]struct{}{}
+	b.mu.Unlock()
+
+	// run ends once the listener gave up its connection
+	return b.listener.Close()
+}
+
+func (b *Broker) run() {
+	// blocks until the first connection, or the broker is closed
+	if err := b.listener.Listen(Channel); err != nil {
+		if !b.isClosed() {
+			b.logger.Error("error on listening to order events", zap.Error(err))
+		}
+		return
+	}
+
+	for notification := range b.listener.Notify {
+		// nil after a reconnection, events sent meanwhile are lost
+		if notification == nil {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+			b.logger.Warn("invalid order event", zap.String("payload", notification.Extra), zap.Error(err))
+			continue
+		}
+		b.dispatch(event)
+	}
+}
+
+func (b *Broker) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+func (b *Broker) dispatch(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.OrderId] {
+		select {
+		case ch <- event:
+		default:
+			b.logger.Warn("order event dropped for a slow subscriber", zap.String("order_id", event.OrderId))
+		}
+	}
+}
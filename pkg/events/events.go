@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Channel is the postgres notification channel the events are sent on.
+const Channel = "order_events"
+
+const (
+	// the order moved to another status
+	TypeStatus = "status"
+	// the asynchronous checkout of the order moved to another state
+	TypeCheckout = "checkout"
+)
+
+// Event is a transition of an order, State is the order status or the
+// checkout state depending on Type.
+type Event struct {
+	OrderId string    `json:"order_id"`
+	Type    string    `json:"type"`
+	State   string    `json:"state"`
+	Reason  string    `json:"reason,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Publish sends the event to the listeners of every instance. Within a
+// transaction, it is only sent if the transaction commits.
+func Publish(ctx context.Context, db execer, event Event) error {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, "SELECT pg_notify($1, $2)", Channel, string(payload))
+	return err
+}
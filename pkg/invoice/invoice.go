@@ -0,0 +1,119 @@
+package invoice
+
+import (
+	"fmt"
+	"orderservice/pkg/repo/order"
+	"time"
+)
+
+// Record is the number given to an order when it completes. Numbers are
+// sequential and gap-free within a year.
+type Record struct {
+	OrderId  string
+	Year     int
+	Sequence int
+	IssuedAt time.Time
+}
+
+type Party struct {
+	Name    string
+	Address string
+	VATId   string
+	Country string
+}
+
+type Line struct {
+	Description string
+	Amount      float32
+}
+
+// Payment is how the total was paid, in the currency it was paid in.
+type Payment struct {
+	Description string
+	Amount      float32
+	Currency    string
+}
+
+type TaxLine struct {
+	Country   string
+	Rate      float32
+	Net       float32
+	Amount    float32
+	Inclusive bool
+}
+
+// Invoice is what gets rendered, built from a record and its order.
+type Invoice struct {
+	Number    string
+	IssuedAt  time.Time
+	OrderId   string
+	Currency  string
+	Seller    Party
+	Buyer     Party
+	Lines     []Line
+	Discounts []Line
+	Taxes     []TaxLine
+	Subtotal  float32
+	Total     float32
+	Payments  []Payment
+}
+
+// Number formats the invoice number, e.g. INV-2024-000042.
+func Number(prefix string, year int, sequence int) string {
+	return fmt.Sprintf("%s%d-%06d", prefix, year, sequence)
+}
+
+// Builder turns orders into invoices of the seller.
+type Builder struct {
+	prefix string
+	seller Party
+}
+
+func NewBuilder(prefix string, seller Party) *Builder {
+	return &Builder{
+		prefix: prefix,
+		seller: seller,
+	}
+}
+
+func (b Builder) Build(record *Record, o *order.Order) *Invoice {
+	inv := &Invoice{
+		Number:    Number(b.prefix, record.Year, record.Sequence),
+		IssuedAt:  record.IssuedAt,
+		OrderId:   o.Id,
+		Currency:  o.Currency,
+		Seller:    b.seller,
+		Buyer:     Party{Name: o.UserId, Country: o.TaxEvidence.BillingCountry},
+		Lines:     []Line{},
+		Discounts: []Line{},
+		Taxes:     []TaxLine{},
+		Subtotal:  o.Subtotal,
+		Total:     o.Total,
+		Payments:  []Payment{},
+	}
+	// the billing address of the card, the tax country for the orders paid
+	// without one
+	if inv.Buyer.Country == "" {
+		inv.Buyer.Country = o.TaxEvidence.Country
+	}
+	for _, item := range o.Items {
+		inv.Lines = append(inv.Lines, Line{Description: item.Name, Amount: item.Price})
+	}
+	for _, d := range o.Discounts {
+		description := d.Code
+		if d.Description != "" {
+			description = d.Code + " - " + d.Description
+		}
+		inv.Discounts = append(inv.Discounts, Line{Description: description, Amount: d.Amount})
+	}
+	for _, t := range o.Taxes {
+		inv.Taxes = append(inv.Taxes, TaxLine(t))
+	}
+	if o.WalletAmount > 0 {
+		inv.Payments = append(inv.Payments, Payment{Description: "Store credit", Amount: o.WalletAmount, Currency: o.Currency})
+	}
+	if o.PaymentAmount != nil && o.PaymentId != nil {
+		inv.Payments = append(inv.Payments, Payment{Description: "Card, payment " + *o.PaymentId, Amount: *o.PaymentAmount, Currency: o.PaymentCurrency})
+	}
+	return inv
+}
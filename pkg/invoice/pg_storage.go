@@ -0,0 +1,48 @@
+package invoice
+
+import (
+	"context"
+	"database/sql"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
+)
+
+type PGInvoiceStorage struct {
+	db *sql.DB
+}
+
+func NewPGInvoiceStorage(url string) (*PGInvoiceStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGInvoiceStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGInvoiceStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGInvoiceStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGInvoiceStorage) Get(ctx context.Context, orderId string) (*Record, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Get", "PGInvoiceStorage")
+	defer span.End()
+
+	record := new(Record)
+	row := s.db.QueryRowContext(ctx, "SELECT order_id, year, sequence, issued_at FROM invoices WHERE order_id = $1", orderId)
+	if err := row.Scan(&record.OrderId, &record.Year, &record.Sequence, &record.IssuedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
@@ -0,0 +1,109 @@
+package invoice
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var funcs = template.FuncMap{
+	"money": formatMoney,
+	"rate":  formatRate,
+	"date":  formatDate,
+}
+
+var htmlTemplate = template.Must(template.New("invoice.html.tmpl").Funcs(funcs).ParseFS(templates, "templates/invoice.html.tmpl"))
+
+func RenderHTML(w io.Writer, inv *Invoice) error {
+	return htmlTemplate.Execute(w, inv)
+}
+
+// RenderPDF lays out the same content as the HTML template on an A4 page.
+func RenderPDF(w io.Writer, inv *Invoice) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle("Invoice "+inv.Number, true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, tr("Invoice "+inv.Number), "", 1, "", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, tr("Issued "+formatDate(inv.IssuedAt)+" - Order "+inv.OrderId), "", 1, "", false, 0, "")
+	pdf.Ln(6)
+
+	seller := inv.Seller.Name + "\n" + inv.Seller.Address
+	if inv.Seller.Country != "" {
+		seller += "\n" + inv.Seller.Country
+	}
+	if inv.Seller.VATId != "" {
+		seller += "\nVAT ID " + inv.Seller.VATId
+	}
+	buyer := "Customer " + inv.Buyer.Name
+	if inv.Buyer.Country != "" {
+		buyer += "\n" + inv.Buyer.Country
+	}
+	y := pdf.GetY()
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(95, 6, "Seller", "", 0, "", false, 0, "")
+	pdf.CellFormat(95, 6, "Buyer", "", 1, "", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(95, 5, tr(seller), "", "", false)
+	sellerEnd := pdf.GetY()
+	pdf.SetXY(105, y+6)
+	pdf.MultiCell(95, 5, tr(buyer), "", "", false)
+	if sellerEnd > pdf.GetY() {
+		pdf.SetY(sellerEnd)
+	}
+	pdf.Ln(8)
+
+	row := func(description string, amount string, bold bool) {
+		style := ""
+		if bold {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.CellFormat(140, 7, tr(description), "B", 0, "", false, 0, "")
+		pdf.CellFormat(40, 7, amount, "B", 1, "R", false, 0, "")
+	}
+
+	row("Item", "Amount ("+inv.Currency+")", true)
+	for _, line := range inv.Lines {
+		row(line.Description, formatMoney(line.Amount), false)
+	}
+	row("Subtotal", formatMoney(inv.Subtotal), false)
+	for _, line := range inv.Discounts {
+		row("Discount "+line.Description, "-"+formatMoney(line.Amount), false)
+	}
+	for _, t := range inv.Taxes {
+		description := "VAT " + t.Country + " " + formatRate(t.Rate) + "% on " + formatMoney(t.Net)
+		if t.Inclusive {
+			description += " (included)"
+		}
+		row(description, formatMoney(t.Amount), false)
+	}
+	row("Total", formatMoney(inv.Total)+" "+inv.Currency, true)
+	for _, payment := range inv.Payments {
+		row("Paid with "+payment.Description, formatMoney(payment.Amount)+" "+payment.Currency, false)
+	}
+
+	return pdf.Output(w)
+}
+
+func formatMoney(amount float32) string {
+	return strconv.FormatFloat(float64(amount), 'f', 2, 32)
+}
+
+func formatRate(rate float32) string {
+	return strconv.FormatFloat(float64(rate), 'f', -1, 32)
+}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
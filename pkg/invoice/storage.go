@@ -0,0 +1,10 @@
+package invoice
+
+import "context"
+
+// InvoiceStorage reads the invoice records, which are created by the order
+// storage in the transaction completing the order so no number is lost.
+type InvoiceStorage interface {
+	// Get returns the record of the order or nil when it has no invoice.
+	Get(ctx context.Context, orderId string) (*Record, error)
+}
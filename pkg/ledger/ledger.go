@@ -0,0 +1,119 @@
+package ledger
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrUnbalanced = errors.New("journal entry does not balance")
+
+// Accounts of the ledger. Amounts are in cents of their currency, debits
+// positive and credits negative, so that every entry sums to zero in each
+// currency.
+const (
+	// money received from the payment service
+	AccountCash = "cash"
+	// money converted between currencies, its balance in each currency is
+	// the exposure to the exchange rate
+	AccountFXConversion = "fx_conversion"
+	// store credit owed to the users
+	AccountStoreCredit = "store_credit"
+	// sales before discounts, without tax
+	AccountRevenue = "revenue"
+	// discounts granted on sales, a contra revenue account
+	AccountDiscounts = "discounts"
+	// tax collected and owed to the tax authorities
+	AccountTaxPayable = "tax_payable"
+)
+
+// Accounts lists the accounts of the ledger.
+var Accounts = []string{AccountCash, AccountFXConversion, AccountStoreCredit, AccountRevenue, AccountDiscounts, AccountTaxPayable}
+
+const (
+	KindCapture    = "capture"
+	KindRefund     = "refund"
+	KindChargeback = "chargeback"
+)
+
+type Posting struct {
+	Account  string `json:"account"`
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+// Entry is a journal entry, recorded once and never changed; a refund is a
+// new entry reversing the capture.
+type Entry struct {
+	Id        string    `json:"id"`
+	OrderId   string    `json:"order_id"`
+	Kind      string    `json:"kind"`
+	Postings  []Posting `json:"postings"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Add appends a posting, amounts of zero are left out.
+func (e *Entry) Add(account string, currency string, amount int64) {
+	if amount == 0 {
+		return
+	}
+	e.Postings = append(e.Postings, Posting{Account: account, Currency: currency, Amount: amount})
+}
+
+// Balanced tells whether the postings of the entry sum to zero in each
+// currency.
+func (e Entry) Balanced() bool {
+	sums := map[string]int64{}
+	for _, p := range e.Postings {
+		sums[p.Currency] += p.Amount
+	}
+	for _, sum := range sums {
+		if sum != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Reversed returns the postings of e with debits and credits swapped.
+func (e Entry) Reversed() []Posting {
+	postings := []Posting{}
+	for _, p := range e.Postings {
+		postings = append(postings, Posting{Account: p.Account, Currency: p.Currency, Amount: -p.Amount})
+	}
+	return postings
+}
+
+// Balance is the sum of the postings of an account in a currency.
+type Balance struct {
+	Account  string
+	Currency string
+	Debit    int64
+	Credit   int64
+}
+
+// Net is the balance with credits as negative amounts.
+func (b Balance) Net() int64 {
+	return b.Debit - b.Credit
+}
+
+// Period narrows balances and checks to the entries recorded in
+// [From, To), an unset bound is open.
+type Period struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// Imbalance is a journal entry whose postings don't sum to zero in
+// Currency.
+type Imbalance struct {
+	EntryId  string
+	OrderId  string
+	Currency string
+	Amount   int64
+}
+
+// Cents converts an amount to cents of its currency.
+func Cents(amount float32) int64 {
+	return int64(math.Round(float64(amount) * 100))
+}
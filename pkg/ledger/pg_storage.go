@@ -0,0 +1,191 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
+	"strings"
+)
+
+type PGLedgerStorage struct {
+	db *sql.DB
+}
+
+func NewPGLedgerStorage(url string) (*PGLedgerStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGLedgerStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGLedgerStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGLedgerStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGLedgerStorage) Balances(ctx context.Context, account string, period Period) ([]Balance, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Balances", "PGLedgerStorage")
+	defer span.End()
+
+	conditions, args := periodConditions(period, []any{account})
+	query := fmt.Sprintf(`SELECT currency, COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0), COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0)
+		FROM ledger_postings JOIN ledger_entries ON ledger_entries.id = ledger_postings.entry_id
+		WHERE %s GROUP BY currency ORDER BY currency`, strings.Join(append([]string{"account = $1"}, conditions...), " AND "))
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := []Balance{}
+	for rows.Next() {
+		b := Balance{Account: account}
+		if err := rows.Scan(&b.Currency, &b.Debit, &b.Credit); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+func (s PGLedgerStorage) Entries(ctx context.Context, orderId string) ([]Entry, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Entries", "PGLedgerStorage")
+	defer span.End()
+
+	return entries(ctx, s.db, orderId, "")
+}
+
+func (s PGLedgerStorage) Check(ctx context.Context, period Period) ([]Imbalance, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Check", "PGLedgerStorage")
+	defer span.End()
+
+	// entries without postings balance trivially, they are never recorded
+	conditions, args := periodConditions(period, []any{})
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	query := fmt.Sprintf(`SELECT ledger_entries.id, ledger_entries.order_id, currency, SUM(amount)
+		FROM ledger_postings JOIN ledger_entries ON ledger_entries.id = ledger_postings.entry_id
+		%s GROUP BY ledger_entries.id, ledger_entries.order_id, currency HAVING SUM(amount) <> 0
+		ORDER BY ledger_entries.id, currency`, where)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imbalances := []Imbalance{}
+	for rows.Next() {
+		var i Imbalance
+		if err := rows.Scan(&i.EntryId, &i.OrderId, &i.Currency, &i.Amount); err != nil {
+			return nil, err
+		}
+		imbalances = append(imbalances, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return imbalances, nil
+}
+
+// Record appends the journal entry within tx, so that it is recorded with
+// the order transition it comes from. Entries that don't balance are
+// refused with ErrUnbalanced.
+func Record(ctx context.Context, tx *sql.Tx, entry *Entry) error {
+	if !entry.Balanced() {
+		return ErrUnbalanced
+	}
+	if len(entry.Postings) == 0 {
+		return nil
+	}
+
+	err := tx.QueryRowContext(ctx, "INSERT INTO ledger_entries (order_id, kind) VALUES ($1, $2) RETURNING id, created_at",
+		entry.OrderId, entry.Kind).Scan(&entry.Id, &entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range entry.Postings {
+		_, err := tx.ExecContext(ctx, "INSERT INTO ledger_postings (entry_id, account, currency, amount) VALUES ($1, $2, $3, $4)",
+			entry.Id, p.Account, p.Currency, p.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find returns the entries of an order of a kind within tx.
+func Find(ctx context.Context, tx *sql.Tx, orderId string, kind string) ([]Entry, error) {
+	return entries(ctx, tx, orderId, kind)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// entries loads the entries of an order with their postings, of any kind
+// when kind is empty.
+func entries(ctx context.Context, db queryer, orderId string, kind string) ([]Entry, error) {
+	query := `SELECT ledger_entries.id, kind, created_at, account, currency, amount
+		FROM ledger_entries JOIN ledger_postings ON ledger_postings.entry_id = ledger_entries.id
+		WHERE order_id = $1 AND ($2 = '' OR kind = $2) ORDER BY created_at, ledger_entries.id, account, currency`
+	rows, err := db.QueryContext(ctx, query, orderId, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var p Posting
+		if err := rows.Scan(&e.Id, &e.Kind, &e.CreatedAt, &p.Account, &p.Currency, &p.Amount); err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 || entries[len(entries)-1].Id != e.Id {
+			e.OrderId = orderId
+			entries = append(entries, e)
+		}
+		last := &entries[len(entries)-1]
+		last.Postings = append(last.Postings, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func periodConditions(period Period, args []any) ([]string, []any) {
+	conditions := []string{}
+	if period.From != nil {
+		args = append(args, *period.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if period.To != nil {
+		args = append(args, *period.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	return conditions, args
+}
@@ -0,0 +1,13 @@
+package ledger
+
+import "context"
+
+type LedgerStorage interface {
+	// Balances sums the postings of the account in the period, one balance
+	// per currency.
+	Balances(ctx context.Context, account string, period Period) ([]Balance, error)
+	// Entries returns the journal entries of an order, oldest first.
+	Entries(ctx context.Context, orderId string) ([]Entry, error)
+	// Check returns the journal entries of the period that don't balance.
+	Check(ctx context.Context, period Period) ([]Imbalance, error)
+}
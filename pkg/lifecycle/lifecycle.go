@@ -0,0 +1,83 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type hook struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Manager runs the service until it gets SIGINT/SIGTERM or the main
+// function fails, then stops the registered components in registration
+// order within the shutdown timeout.
+type Manager struct {
+	logger  *zap.Logger
+	timeout time.Duration
+	hooks   []hook
+}
+
+func NewManager(logger *zap.Logger, timeout time.Duration) *Manager {
+	return &Manager{
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// Register adds a component to stop on shutdown. Components that accept
+// work (the http server) should be registered before the ones they depend
+// on (workers, storages, tracer).
+func (m *Manager) Register(name string, stop func(ctx context.Context) error) {
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+func (m *Manager) Run(run func() error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- run()
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		m.logger.Info("shutdown signal received")
+	case runErr = <-errCh:
+		if runErr != nil {
+			m.logger.Error("service stopped unexpectedly", zap.Error(runErr))
+		}
+	}
+
+	// a second signal skips the graceful shutdown
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	return errors.Join(runErr, m.shutdown(shutdownCtx))
+}
+
+func (m *Manager) shutdown(ctx context.Context) error {
+	var errs []error
+	for _, h := range m.hooks {
+		start := time.Now()
+		if err := h.stop(ctx); err != nil {
+			m.logger.Error("error on stopping component", zap.String("component", h.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			continue
+		}
+		m.logger.Info("component stopped", zap.String("component", h.name), zap.Duration("took", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
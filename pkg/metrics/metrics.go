@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PoolStats is a snapshot of a connection pool, filled by the storages so
+// they don't depend on the metrics backend.
+type PoolStats struct {
+	Open      int
+	InUse     int
+	Idle      int
+	WaitCount int64
+	Timeouts  int64
+}
+
+// SQLPoolStats reads the snapshot of a database/sql pool.
+func SQLPoolStats(stats sql.DBStats) PoolStats {
+	return PoolStats{
+		Open:      stats.OpenConnections,
+		InUse:     stats.InUse,
+		Idle:      stats.Idle,
+		WaitCount: stats.WaitCount,
+	}
+}
+
+// Metrics records the technical and business metrics of the service.
+type Metrics interface {
+	ObserveHTTPRequest(method, route string, status int, duration time.Duration)
+	ObserveClientRequest(client, operation string, duration time.Duration, failed bool)
+	RegisterPool(name string, stats func() PoolStats)
+
+	CartUpdated()
+	CheckoutStarted()
+	CheckoutSucceeded(currency string, amount float64)
+	CheckoutDeclined()
+}
+
+type Nop struct{}
+
+func (Nop) ObserveHTTPRequest(method, route string, status int, duration time.Duration)        {}
+func (Nop) ObserveClientRequest(client, operation string, duration time.Duration, failed bool) {}
+func (Nop) RegisterPool(name string, stats func() PoolStats)                                   {}
+func (Nop) CartUpdated()                                                                       {}
+func (Nop) CheckoutStarted()                                                                   {}
+func (Nop) CheckoutSucceeded(currency string, amount float64)                                  {}
+func (Nop) CheckoutDeclined()                                                                  {}
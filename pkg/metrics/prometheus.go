@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "order"
+
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	httpRequests       *prometheus.CounterVec
+	httpDuration       *prometheus.HistogramVec
+	clientRequests     *prometheus.CounterVec
+	clientDuration     *prometheus.HistogramVec
+	cartsUpdated       prometheus.Counter
+	checkoutsStarted   prometheus.Counter
+	checkoutsSucceeded prometheus.Counter
+	checkoutsDeclined  prometheus.Counter
+	revenue            *prometheus.CounterVec
+}
+
+func NewPrometheus() *Prometheus {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	p := &Prometheus{
+		registry: registry,
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of handled http requests.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of handled http requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		clientRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "client_requests_total",
+			Help:      "Number of requests made to downstream services.",
+		}, []string{"client", "operation", "result"}),
+		clientDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "client_request_duration_seconds",
+			Help:      "Duration of requests made to downstream services.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"client", "operation"}),
+		cartsUpdated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "carts_updated_total",
+			Help:      "Number of cart updates.",
+		}),
+		checkoutsStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "checkouts_started_total",
+			Help:      "Number of started checkouts.",
+		}),
+		checkoutsSucceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "checkouts_succeeded_total",
+			Help:      "Number of completed checkouts.",
+		}),
+		checkoutsDeclined: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "checkouts_declined_total",
+			Help:      "Number of checkouts whose payment was declined.",
+		}),
+		revenue: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "revenue_total",
+			Help:      "Charged amount of completed checkouts.",
+		}, []string{"currency"}),
+	}
+
+	registry.MustRegister(
+		p.httpRequests,
+		p.httpDuration,
+		p.clientRequests,
+		p.clientDuration,
+		p.cartsUpdated,
+		p.checkoutsStarted,
+		p.checkoutsSucceeded,
+		p.checkoutsDeclined,
+		p.revenue,
+	)
+	return p
+}
+
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{Registry: p.registry})
+}
+
+func (p *Prometheus) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	p.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	p.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) ObserveClientRequest(client, operation string, duration time.Duration, failed bool) {
+	result := "success"
+	if failed {
+		result = "error"
+	}
+	p.clientRequests.WithLabelValues(client, operation, result).Inc()
+	p.clientDuration.WithLabelValues(client, operation).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) RegisterPool(name string, stats func() PoolStats) {
+	labels := prometheus.Labels{"pool": name}
+	gauge := func(metric, help string, value func(PoolStats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        metric,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 {
+			return value(stats())
+		})
+	}
+	counter := func(metric, help string, value func(PoolStats) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        metric,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 {
+			return value(stats())
+		})
+	}
+
+	p.registry.MustRegister(
+		gauge("pool_open_connections", "Number of open connections.", func(s PoolStats) float64 { return float64(s.Open) }),
+		gauge("pool_in_use_connections", "Number of connections in use.", func(s PoolStats) float64 { return float64(s.InUse) }),
+		gauge("pool_idle_connections", "Number of idle connections.", func(s PoolStats) float64 { return float64(s.Idle) }),
+		counter("pool_wait_total", "Number of times a connection was waited for.", func(s PoolStats) float64 { return float64(s.WaitCount) }),
+		counter("pool_timeouts_total", "Number of times waiting for a connection timed out.", func(s PoolStats) float64 { return float64(s.Timeouts) }),
+	)
+}
+
+func (p *Prometheus) CartUpdated() {
+	p.cartsUpdated.Inc()
+}
+
+func (p *Prometheus) CheckoutStarted() {
+	p.checkoutsStarted.Inc()
+}
+
+func (p *Prometheus) CheckoutSucceeded(currency string, amount float64) {
+	p.checkoutsSucceeded.Inc()
+	p.revenue.WithLabelValues(currency).Add(amount)
+}
+
+func (p *Prometheus) CheckoutDeclined() {
+	p.checkoutsDeclined.Inc()
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type memoryWindow struct {
+	hits   []time.Time
+	window time.Duration
+}
+
+// MemoryLimiter keeps a sliding window log per key in process memory. It is
+// only accurate for a single replica.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		windows:   map[string]*memoryWindow{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	w, ok := m.windows[key]
+	if !ok {
+		w = &memoryWindow{}
+		m.windows[key] = w
+	}
+	w.window = window
+
+	// drop the hits that left the window
+	start := 0
+	for start < len(w.hits) && now.Sub(w.hits[start]) >= window {
+		start++
+	}
+	w.hits = w.hits[start:]
+
+	allowed := len(w.hits) < limit
+	if allowed {
+		w.hits = append(w.hits, now)
+	}
+
+	resetAfter := window
+	if len(w.hits) > 0 {
+		resetAfter = w.hits[0].Add(window).Sub(now)
+	}
+
+	return Result{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  limit - len(w.hits),
+		ResetAfter: resetAfter,
+	}, nil
+}
+
+// sweep forgets the keys without hits in their window so idle clients
+// don't pile up.
+func (m *MemoryLimiter) sweep(now time.Time) {
+	for key, w := range m.windows {
+		if len(w.hits) == 0 || now.Sub(w.hits[len(w.hits)-1]) >= w.window {
+			delete(m.windows, key)
+		}
+	}
+	m.lastSweep = now
+}
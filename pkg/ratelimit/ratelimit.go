@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	KeyByUser   = "user"
+	KeyByIP     = "ip"
+	KeyByGlobal = "global"
+
+	// AnyRoute matches every route.
+	AnyRoute = "*"
+)
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+}
+
+// Limiter counts hits of key in a sliding window.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// Policy limits a route (METHOD and path template, e.g. "POST /api/v1/cart",
+// or "*") to Limit requests per Window for each user, ip or for everybody.
+type Policy struct {
+	Route  string
+	KeyBy  string
+	Limit  int
+	Window time.Duration
+}
+
+func (p Policy) Matches(method, path string) bool {
+	return p.Route == AnyRoute || p.Route == method+" "+path
+}
+
+// ParsePolicies reads policies in the form
+// "POST /api/v1/cart=user:30/1m;*=ip:300/1m".
+func ParsePolicies(value string) ([]Policy, error) {
+	policies := []Policy{}
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, rule, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit policy %q: missing '='", entry)
+		}
+		keyBy, rate, ok := strings.Cut(rule, ":")
+		if !ok {
+			return nil, fmt.Errorf("rate limit policy %q: missing ':'", entry)
+		}
+		limit, window, ok := strings.Cut(rate, "/")
+		if !ok {
+			return nil, fmt.Errorf("rate limit policy %q: missing '/'", entry)
+		}
+
+		if keyBy != KeyByUser && keyBy != KeyByIP && keyBy != KeyByGlobal {
+			return nil, fmt.Errorf("rate limit policy %q: unknown key %q", entry, keyBy)
+		}
+		limitValue, err := strconv.Atoi(limit)
+		if err != nil || limitValue < 1 {
+			return nil, fmt.Errorf("rate limit policy %q: invalid limit %q", entry, limit)
+		}
+		windowValue, err := time.ParseDuration(window)
+		if err != nil || windowValue <= 0 {
+			return nil, fmt.Errorf("rate limit policy %q: invalid window %q", entry, window)
+		}
+
+		policies = append(policies, Policy{
+			Route:  strings.TrimSpace(route),
+			KeyBy:  keyBy,
+			Limit:  limitValue,
+			Window: windowValue,
+		})
+	}
+	return policies, nil
+}
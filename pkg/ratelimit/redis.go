@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindow trims the hits that left the window, adds the current hit
+// when there is room and returns {allowed, count, reset in ms} atomically.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+// RedisLimiter keeps a sliding window log per key in a redis sorted set so
+// every replica shares the same counters.
+type RedisLimiter struct {
+	redisClient *redis.Client
+	prefix      string
+}
+
+func NewRedisLimiter(url string) (*RedisLimiter, error) {
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter := &RedisLimiter{
+		redisClient: redis.NewClient(opt),
+		prefix:      "ratelimit:",
+	}
+	return limiter, nil
+}
+
+func (r RedisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := time.Now().UnixMilli()
+	values, err := slidingWindow.Run(ctx, r.redisClient, []string{r.prefix + key}, now, window.Milliseconds(), limit, uuid.NewString()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  limit - int(values[1]),
+		ResetAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+func (r RedisLimiter) Close() error {
+	return r.redisClient.Close()
+}
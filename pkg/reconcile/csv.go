@@ -0,0 +1,25 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes the mismatches of the report, one per row after a header.
+func WriteCSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"order_id", "order_status", "kind", "payment_id", "detail", "repaired"}); err != nil {
+		return err
+	}
+
+	for _, m := range report.Mismatches {
+		err := writer.Write([]string{m.OrderId, m.OrderStatus, m.Kind, m.PaymentId, m.Detail, strconv.FormatBool(m.Repaired)})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
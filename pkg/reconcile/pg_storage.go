@@ -0,0 +1,60 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"orderservice/pkg/telemetry"
+)
+
+type PGReportStorage struct {
+	db *sql.DB
+}
+
+func NewPGReportStorage(url string) (*PGReportStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGReportStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGReportStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGReportStorage) Save(ctx context.Context, report *Report) error {
+	span, ctx := telemetry.StartSpan(ctx, "Save", "PGReportStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO reconciliation_runs (window_from, window_to, started_at, finished_at, checked, failed, repaired, mismatches)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		report.From, report.To, report.StartedAt, report.FinishedAt, report.Checked, report.Failed, report.Repaired, len(report.Mismatches),
+	).Scan(&report.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range report.Mismatches {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO reconciliation_mismatches (run_id, order_id, order_status, kind, payment_id, detail, repaired)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			report.Id, m.OrderId, m.OrderStatus, m.Kind, m.PaymentId, m.Detail, m.Repaired,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,300 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderservice/pkg/client/payment"
+	"orderservice/pkg/ledger"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/repo/wallet"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// a paid order whose payment the payment service doesn't know
+	KindUnknownPayment = "unknown_payment"
+	// a completed order whose payment was not captured
+	KindNotCaptured = "not_captured"
+	// a paid order charged another amount than the one it recorded
+	KindAmountMismatch = "amount_mismatch"
+	// an order charged but not completed
+	KindChargedNotCompleted = "charged_not_completed"
+	// an order charged more than it should have been
+	KindDuplicateCharge = "duplicate_charge"
+	// an order not paid, and not charged, still holding the store credit
+	// spent on it
+	KindCreditHeld = "credit_held"
+)
+
+// Payments looks payments up in the payment service.
+type Payments interface {
+	GetPayment(ctx context.Context, id string) (*payment.Payment, error)
+	FindPayments(ctx context.Context, orderId string) ([]payment.Payment, error)
+}
+
+type Mismatch struct {
+	OrderId     string
+	OrderStatus string
+	Kind        string
+	PaymentId   string
+	Detail      string
+	Repaired    bool
+}
+
+// Report is the outcome of a reconciliation of the orders created in
+// [From, To). Failed counts the orders whose payments couldn't be looked up.
+type Report struct {
+	Id         string
+	From       time.Time
+	To         time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Checked    int
+	Failed     int
+	Repaired   int
+	Mismatches []Mismatch
+}
+
+type Reconciler struct {
+	logger   *zap.Logger
+	orders   order.OrderStorage
+	wallets  wallet.WalletStorage
+	payments Payments
+	pageSize int
+	// orders younger than this may still be completed by their checkout,
+	// they are not repaired
+	minAge time.Duration
+}
+
+func NewReconciler(logger *zap.Logger, orders order.OrderStorage, wallets wallet.WalletStorage, payments Payments, pageSize int, minAge time.Duration) *Reconciler {
+	return &Reconciler{
+		logger:   logger,
+		orders:   orders,
+		wallets:  wallets,
+		payments: payments,
+		pageSize: pageSize,
+		minAge:   minAge,
+	}
+}
+
+// Run checks the payments of the orders created in [from, to) against the
+// payment service. With repair, the orders charged but not completed are
+// completed when it is safe, see repairable, and the store credit held by
+// orders never charged is given back.
+func (r *Reconciler) Run(ctx context.Context, from time.Time, to time.Time, repair bool) (*Report, error) {
+	report := &Report{
+		From:       from,
+		To:         to,
+		StartedAt:  time.Now(),
+		Mismatches: []Mismatch{},
+	}
+
+	var after *order.Cursor
+	for {
+		orders, err := r.orders.ListCreated(ctx, from, to, after, r.pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range orders {
+			o := &orders[i]
+			report.Checked++
+
+			mismatches, charged, err := r.check(ctx, o)
+			if err != nil {
+				r.logger.Warn("error on looking payments of order up", zap.String("order_id", o.Id), zap.Error(err))
+				report.Failed++
+				continue
+			}
+
+			for _, m := range mismatches {
+				if repair && m.Kind == KindChargedNotCompleted {
+					m.Repaired, err = r.repair(ctx, o, charged)
+					if err != nil {
+						r.logger.Error("error on repairing order", zap.String("order_id", o.Id), zap.Error(err))
+					}
+				}
+				if repair && m.Kind == KindCreditHeld {
+					m.Repaired, err = r.releaseCredit(ctx, o)
+					if err != nil {
+						r.logger.Error("error on giving store credit back", zap.String("order_id", o.Id), zap.Error(err))
+					}
+				}
+				if m.Repaired {
+					report.Repaired++
+				}
+				report.Mismatches = append(report.Mismatches, m)
+			}
+		}
+
+		if len(orders) < r.pageSize {
+			break
+		}
+		last := orders[len(orders)-1]
+		after = &order.Cursor{CreatedAt: last.CreatedAt, Id: last.Id}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// check compares the order with its payments, and returns the mismatches
+// along with the payments that charged it.
+func (r *Reconciler) check(ctx context.Context, o *order.Order) ([]Mismatch, []payment.Payment, error) {
+	payments, err := r.payments.FindPayments(ctx, o.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// a refunded or charged back payment was charged first
+	charged := []payment.Payment{}
+	pending := false
+	for _, p := range payments {
+		if p.Status == payment.StatusCaptured || p.Status == payment.StatusRefunded || p.Status == payment.StatusChargedBack {
+			charged = append(charged, p)
+		}
+		if p.Status == payment.StatusPending {
+			pending = true
+		}
+	}
+
+	mismatch := func(kind string, paymentId string, detail string) Mismatch {
+		return Mismatch{OrderId: o.Id, OrderStatus: o.Status, Kind: kind, PaymentId: paymentId, Detail: detail}
+	}
+	mismatches := []Mismatch{}
+
+	paid := o.Status == order.StatusCompleted || o.Status == order.StatusRefunded || o.Status == order.StatusChargedBack
+	if !paid {
+		if len(charged) > 0 {
+			mismatches = append(mismatches, mismatch(KindChargedNotCompleted, charged[0].Id, fmt.Sprintf("%d charge(s) for an order %s", len(charged), o.Status)))
+		}
+		if len(charged) > 1 {
+			mismatches = append(mismatches, mismatch(KindDuplicateCharge, charged[1].Id, "charged "+paymentIds(charged)))
+		}
+		// nothing gives the credit back to an order left after an unknown
+		// outcome of its charge, once its checkout is over
+		if len(charged) == 0 && !pending && o.WalletAmount > 0 && o.CreatedAt.Before(time.Now().Add(-r.minAge)) {
+			mismatches = append(mismatches, mismatch(KindCreditHeld, "", fmt.Sprintf("%.2f %s of store credit spent on an order %s", o.WalletAmount, o.Currency, o.Status)))
+		}
+		return mismatches, charged, nil
+	}
+
+	// paid with store credit only
+	if o.PaymentId == nil {
+		if len(charged) > 0 {
+			mismatches = append(mismatches, mismatch(KindDuplicateCharge, charged[0].Id, "paid with store credit but charged "+paymentIds(charged)))
+		}
+		return mismatches, charged, nil
+	}
+
+	p, err := r.payments.GetPayment(ctx, *o.PaymentId)
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		mismatches = append(mismatches, mismatch(KindUnknownPayment, *o.PaymentId, "payment service doesn't know the payment"))
+	} else if err != nil {
+		return nil, nil, err
+	} else {
+		if o.Status == order.StatusCompleted && p.Status != payment.StatusCaptured {
+			mismatches = append(mismatches, mismatch(KindNotCaptured, p.Id, "payment is "+p.Status))
+		}
+		if o.PaymentAmount != nil && (ledger.Cents(*o.PaymentAmount) != ledger.Cents(float32(p.Amount)) || o.PaymentCurrency != p.Currency) {
+			detail := fmt.Sprintf("recorded %.2f %s, charged %.2f %s", *o.PaymentAmount, o.PaymentCurrency, p.Amount, p.Currency)
+			mismatches = append(mismatches, mismatch(KindAmountMismatch, p.Id, detail))
+		}
+	}
+
+	others := []payment.Payment{}
+	for _, c := range charged {
+		if c.Id != *o.PaymentId {
+			others = append(others, c)
+		}
+	}
+	if len(others) > 0 {
+		mismatches = append(mismatches, mismatch(KindDuplicateCharge, others[0].Id, "also charged "+paymentIds(others)))
+	}
+
+	return mismatches, charged, nil
+}
+
+// repair completes an order charged but not completed when it is safe: it
+// is still waiting for its payment and old enough for its checkout to be
+// over, it was charged once for its card leg and no store credit spent on
+// it was given back, so the charge pays the whole order.
+func (r *Reconciler) repair(ctx context.Context, o *order.Order, charged []payment.Payment) (bool, error) {
+	if !repairable(o, charged, time.Now().Add(-r.minAge)) {
+		return false, nil
+	}
+
+	reversed, err := r.wallets.Reversed(ctx, o.Id)
+	if err != nil {
+		return false, err
+	}
+	if reversed > 0 {
+		return false, nil
+	}
+
+	// completed or given up since it was listed
+	p := charged[0]
+	err = r.orders.Complete(ctx, o.Id, order.Payment{Id: p.Id, Amount: float32(p.Amount), Currency: p.Currency})
+	if errors.Is(err, order.ErrNotPayable) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.logger.Info("order completed by reconciliation", zap.String("order_id", o.Id), zap.String("payment_id", p.Id))
+	return true, nil
+}
+
+// releaseCredit gives the store credit held by an order never charged back
+// to the user. The order must still not be paid, its late completion finds
+// the credit given back and is left to the next reconciliation.
+func (r *Reconciler) releaseCredit(ctx context.Context, o *order.Order) (bool, error) {
+	current, err := r.orders.Get(ctx, o.Id)
+	if err != nil {
+		return false, err
+	}
+	if current == nil || current.Status != o.Status {
+		return false, nil
+	}
+
+	reversed, err := r.wallets.Reverse(ctx, o.Id, "order not paid")
+	if err != nil {
+		return false, err
+	}
+	if err := r.orders.SetWalletAmount(ctx, o.Id, 0); err != nil && !errors.Is(err, order.ErrNotPayable) {
+		return false, err
+	}
+
+	r.logger.Info("store credit given back by reconciliation", zap.String("order_id", o.Id), zap.Float32("amount", reversed))
+	return reversed > 0, nil
+}
+
+// repairable tells whether the order created before createdBefore can be
+// completed with its only charge. The charge must be the card leg recorded
+// before the card was charged, an order without one was never charged by
+// the checkout.
+func repairable(o *order.Order, charged []payment.Payment, createdBefore time.Time) bool {
+	if o.Status != order.StatusReady && o.Status != order.StatusApproved {
+		return false
+	}
+	if !o.CreatedAt.Before(createdBefore) {
+		return false
+	}
+	if len(charged) != 1 || charged[0].Status != payment.StatusCaptured {
+		return false
+	}
+	return o.ChargedLeg(float32(charged[0].Amount), charged[0].Currency)
+}
+
+func paymentIds(payments []payment.Payment) string {
+	ids := []string{}
+	for _, p := range payments {
+		ids = append(ids, p.Id)
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,8 @@
+package reconcile
+
+import "context"
+
+type ReportStorage interface {
+	// Save stores the report with its mismatches and sets its Id.
+	Save(ctx context.Context, report *Report) error
+}
@@ -1,11 +1,13 @@
 package cart
 
 type CartItem struct {
-	Id    string  `json:"id"`
-	Name  string  `json:"name"`
-	Price float32 `json:"price"`
+	Id     string  `json:"id"`
+	Name   string  `json:"name"`
+	Author string  `json:"author,omitempty"`
+	Price  float32 `json:"price"`
 }
 
 type Cart struct {
-	Items []CartItem `json:"items"`
+	Items     []CartItem `json:"items"`
+	PromoCode string     `json:"promo_code,omitempty"`
 }
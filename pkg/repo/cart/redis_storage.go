@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
 
 	"github.com/redis/go-redis/v9"
-	"go.elastic.co/apm/v2"
+	"go.uber.org/zap"
 )
 
 type RedisCartStorage struct {
@@ -27,7 +30,7 @@ func NewRedisCartStorage(url string) (*RedisCartStorage, error) {
 }
 
 func (r RedisCartStorage) Get(ctx context.Context, key string) (*Cart, error) {
-	span, ctx := apm.StartSpan(ctx, "Get", "RedisCartStorage")
+	span, ctx := telemetry.StartSpan(ctx, "Get", "RedisCartStorage")
 	defer span.End()
 
 	value, err := r.redisClient.Get(ctx, key).Result()
@@ -47,7 +50,7 @@ func (r RedisCartStorage) Get(ctx context.Context, key string) (*Cart, error) {
 }
 
 func (r RedisCartStorage) Set(ctx context.Context, key string, cart *Cart) error {
-	span, ctx := apm.StartSpan(ctx, "Set", "RedisCartStorage")
+	span, ctx := telemetry.StartSpan(ctx, "Set", "RedisCartStorage")
 	defer span.End()
 
 	j, err := json.Marshal(cart)
@@ -57,13 +60,41 @@ func (r RedisCartStorage) Set(ctx context.Context, key string, cart *Cart) error
 
 	value := string(j)
 	err = r.redisClient.Set(ctx, key, value, 0).Err()
-	return err
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug("cart stored", zap.String("key", key), zap.Int("items", len(cart.Items)))
+	return nil
 }
 
 func (r RedisCartStorage) Delete(ctx context.Context, key string) error {
-	span, ctx := apm.StartSpan(ctx, "Delete", "RedisCartStorage")
+	span, ctx := telemetry.StartSpan(ctx, "Delete", "RedisCartStorage")
 	defer span.End()
 
 	err := r.redisClient.Del(ctx, key).Err()
-	return err
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug("cart deleted", zap.String("key", key))
+	return nil
+}
+
+func (r RedisCartStorage) Close() error {
+	return r.redisClient.Close()
+}
+
+func (r RedisCartStorage) Ping(ctx context.Context) error {
+	return r.redisClient.Ping(ctx).Err()
+}
+
+func (r RedisCartStorage) PoolStats() metrics.PoolStats {
+	stats := r.redisClient.PoolStats()
+	return metrics.PoolStats{
+		Open:     int(stats.TotalConns),
+		InUse:    int(stats.TotalConns - stats.IdleConns),
+		Idle:     int(stats.IdleConns),
+		Timeouts: int64(stats.Timeouts),
+	}
 }
@@ -0,0 +1,87 @@
+package dispute
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	StateOpened = "opened"
+	// evidence was sent to the bank, waiting for its decision
+	StateEvidenceSubmitted = "evidence_submitted"
+	// the bank decided for the store, the entitlements are given back
+	StateWon = "won"
+	// the bank decided for the card holder, the order is charged back
+	StateLost = "lost"
+)
+
+var (
+	ErrNotFound        = errors.New("dispute not found")
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrNotDisputable   = errors.New("only completed orders can be disputed")
+	ErrAlreadyOpen     = errors.New("order already has an open dispute")
+	ErrAlreadyResolved = errors.New("dispute already resolved")
+)
+
+// Dispute is the contest of the payment of an order by the card holder,
+// through their bank.
+type Dispute struct {
+	Id                  string
+	OrderId             string
+	UserId              string
+	PaymentId           string
+	State               string
+	Reason              string
+	Amount              float32
+	Currency            string
+	Evidence            string
+	OpenedAt            time.Time
+	EvidenceSubmittedAt *time.Time
+	ResolvedAt          *time.Time
+}
+
+// Open tells whether the bank hasn't decided yet.
+func (d Dispute) Open() bool {
+	return d.State == StateOpened || d.State == StateEvidenceSubmitted
+}
+
+// transition checks that a dispute can move from a state to another. An
+// open dispute takes evidence, again while the bank hasn't decided, and is
+// resolved once.
+func transition(from string, to string) error {
+	switch from {
+	case StateOpened, StateEvidenceSubmitted:
+	case StateWon, StateLost:
+		return ErrAlreadyResolved
+	default:
+		return fmt.Errorf("unknown dispute state %q", from)
+	}
+
+	switch to {
+	case StateEvidenceSubmitted, StateWon, StateLost:
+		return nil
+	}
+	return fmt.Errorf("dispute can't move to %q", to)
+}
+
+// Opening is a dispute to open on an order. The amount and currency of the
+// payment of the order are used when Amount is zero.
+type Opening struct {
+	OrderId   string
+	PaymentId string
+	Reason    string
+	Amount    float32
+	Currency  string
+}
+
+// Stats counts the disputes of a user, they weigh in the risk check of the
+// next checkouts.
+type Stats struct {
+	UserId       string
+	Total        int
+	Open         int
+	Won          int
+	Lost         int
+	LastOpenedAt *time.Time
+}
@@ -0,0 +1,62 @@
+package dispute
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from    string
+		to      string
+		want    error
+		wantErr bool
+	}{
+		{StateOpened, StateEvidenceSubmitted, nil, false},
+		{StateOpened, StateWon, nil, false},
+		{StateOpened, StateLost, nil, false},
+		{StateEvidenceSubmitted, StateEvidenceSubmitted, nil, false},
+		{StateEvidenceSubmitted, StateWon, nil, false},
+		{StateEvidenceSubmitted, StateLost, nil, false},
+		{StateWon, StateEvidenceSubmitted, ErrAlreadyResolved, true},
+		{StateWon, StateLost, ErrAlreadyResolved, true},
+		{StateLost, StateWon, ErrAlreadyResolved, true},
+		{StateLost, StateLost, ErrAlreadyResolved, true},
+		{StateOpened, StateOpened, nil, true},
+		{StateEvidenceSubmitted, StateOpened, nil, true},
+		{StateOpened, "closed", nil, true},
+		{"closed", StateWon, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			err := transition(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("transition() = %v, want an error: %v", err, tt.wantErr)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("transition() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		state string
+		want  bool
+	}{
+		{StateOpened, true},
+		{StateEvidenceSubmitted, true},
+		{StateWon, false},
+		{StateLost, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			if got := (Dispute{State: tt.state}).Open(); got != tt.want {
+				t.Errorf("Open() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
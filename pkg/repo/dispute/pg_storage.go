@@ -0,0 +1,296 @@
+package dispute
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/telemetry"
+
+	"go.uber.org/zap"
+)
+
+const disputeColumns = "id, order_id, user_id, payment_id, state, reason, amount, currency, evidence, opened_at, evidence_submitted_at, resolved_at"
+
+type PGDisputeStorage struct {
+	db *sql.DB
+}
+
+func NewPGDisputeStorage(url string) (*PGDisputeStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGDisputeStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGDisputeStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGDisputeStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGDisputeStorage) Open(ctx context.Context, opening Opening) (*Dispute, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Open", "PGDisputeStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// locking the order serializes the disputes opened on it, and keeps it
+	// from being refunded meanwhile
+	var userId, status string
+	var paymentId, paymentCurrency sql.NullString
+	var paymentAmount sql.NullFloat64
+	var total float64
+	err = tx.QueryRowContext(ctx, "SELECT user_id, status, payment_id, payment_amount, payment_currency, total FROM orders WHERE id = $1 FOR UPDATE", opening.OrderId).
+		Scan(&userId, &status, &paymentId, &paymentAmount, &paymentCurrency, &total)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	if status != order.StatusCompleted {
+		return nil, ErrNotDisputable
+	}
+
+	var open bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM disputes WHERE order_id = $1 AND state IN ($2, $3))", opening.OrderId, StateOpened, StateEvidenceSubmitted).Scan(&open)
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, ErrAlreadyOpen
+	}
+
+	if opening.PaymentId == "" {
+		opening.PaymentId = paymentId.String
+	}
+	if opening.Amount == 0 {
+		// paid with store credit only, the total is what is disputed
+		opening.Amount, opening.Currency = float32(total), order.Currency
+		if paymentAmount.Valid {
+			opening.Amount, opening.Currency = float32(paymentAmount.Float64), paymentCurrency.String
+		}
+	}
+
+	d, err := scanDispute(tx.QueryRowContext(ctx, `INSERT INTO disputes (order_id, user_id, payment_id, state, reason, amount, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING `+disputeColumns,
+		opening.OrderId, userId, opening.PaymentId, StateOpened, opening.Reason, opening.Amount, opening.Currency))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE entitlements SET suspended_at = now() WHERE order_id = $1 AND revoked_at IS NULL AND suspended_at IS NULL", opening.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Debug("dispute opened", zap.String("order_id", d.OrderId), zap.String("dispute_id", d.Id))
+	return d, nil
+}
+
+func (s PGDisputeStorage) Get(ctx context.Context, id string) (*Dispute, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Get", "PGDisputeStorage")
+	defer span.End()
+
+	d, err := scanDispute(s.db.QueryRowContext(ctx, "SELECT "+disputeColumns+" FROM disputes WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (s PGDisputeStorage) FindOpen(ctx context.Context, orderId string) (*Dispute, error) {
+	span, ctx := telemetry.StartSpan(ctx, "FindOpen", "PGDisputeStorage")
+	defer span.End()
+
+	d, err := scanDispute(s.db.QueryRowContext(ctx, "SELECT "+disputeColumns+" FROM disputes WHERE order_id = $1 AND state IN ($2, $3)", orderId, StateOpened, StateEvidenceSubmitted))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (s PGDisputeStorage) ListByOrder(ctx context.Context, orderId string) ([]Dispute, error) {
+	span, ctx := telemetry.StartSpan(ctx, "ListByOrder", "PGDisputeStorage")
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+disputeColumns+" FROM disputes WHERE order_id = $1 ORDER BY opened_at, id", orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disputes := []Dispute{}
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return disputes, nil
+}
+
+func (s PGDisputeStorage) SubmitEvidence(ctx context.Context, id string, evidence string) (*Dispute, error) {
+	span, ctx := telemetry.StartSpan(ctx, "SubmitEvidence", "PGDisputeStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockFor(ctx, tx, id, StateEvidenceSubmitted); err != nil {
+		return nil, err
+	}
+
+	d, err := scanDispute(tx.QueryRowContext(ctx, `UPDATE disputes SET state = $1, evidence = $2, evidence_submitted_at = now()
+		WHERE id = $3 RETURNING `+disputeColumns, StateEvidenceSubmitted, evidence, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s PGDisputeStorage) Resolve(ctx context.Context, id string, state string) (*Dispute, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Resolve", "PGDisputeStorage")
+	defer span.End()
+
+	if state != StateWon && state != StateLost {
+		return nil, fmt.Errorf("dispute can't be resolved as %q", state)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockFor(ctx, tx, id, state)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := scanDispute(tx.QueryRowContext(ctx, "UPDATE disputes SET state = $1, resolved_at = now() WHERE id = $2 RETURNING "+disputeColumns, state, id))
+	if err != nil {
+		return nil, err
+	}
+
+	switch state {
+	case StateWon:
+		_, err = tx.ExecContext(ctx, "UPDATE entitlements SET suspended_at = NULL WHERE order_id = $1 AND suspended_at IS NOT NULL", current.OrderId)
+		if err != nil {
+			return nil, err
+		}
+	case StateLost:
+		// the order stayed completed, it can't be refunded while disputed
+		if _, err := order.ChargeBack(ctx, tx, current.OrderId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Debug("dispute resolved", zap.String("order_id", d.OrderId), zap.String("dispute_id", d.Id), zap.String("state", d.State))
+	return d, nil
+}
+
+func (s PGDisputeStorage) Stats(ctx context.Context, userId string) (*Stats, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Stats", "PGDisputeStorage")
+	defer span.End()
+
+	stats := &Stats{UserId: userId}
+	var lastOpenedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `SELECT count(*),
+		count(*) FILTER (WHERE state IN ($2, $3)),
+		count(*) FILTER (WHERE state = $4),
+		count(*) FILTER (WHERE state = $5),
+		max(opened_at)
+		FROM disputes WHERE user_id = $1`, userId, StateOpened, StateEvidenceSubmitted, StateWon, StateLost).
+		Scan(&stats.Total, &stats.Open, &stats.Won, &stats.Lost, &lastOpenedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastOpenedAt.Valid {
+		stats.LastOpenedAt = &lastOpenedAt.Time
+	}
+	return stats, nil
+}
+
+// lockFor locks the dispute until the end of tx, it must be able to move to
+// state.
+func lockFor(ctx context.Context, tx *sql.Tx, id string, state string) (*Dispute, error) {
+	d, err := scanDispute(tx.QueryRowContext(ctx, "SELECT "+disputeColumns+" FROM disputes WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := transition(d.State, state); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDispute(row scanner) (*Dispute, error) {
+	d := new(Dispute)
+	var evidenceSubmittedAt, resolvedAt sql.NullTime
+
+	err := row.Scan(&d.Id, &d.OrderId, &d.UserId, &d.PaymentId, &d.State, &d.Reason, &d.Amount, &d.Currency, &d.Evidence, &d.OpenedAt, &evidenceSubmittedAt, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if evidenceSubmittedAt.Valid {
+		d.EvidenceSubmittedAt = &evidenceSubmittedAt.Time
+	}
+	if resolvedAt.Valid {
+		d.ResolvedAt = &resolvedAt.Time
+	}
+
+	return d, nil
+}
@@ -0,0 +1,23 @@
+package dispute
+
+import "context"
+
+// DisputeStorage tracks the disputes along with what they do to their
+// order.
+type DisputeStorage interface {
+	// Open opens a dispute on a completed order and suspends the
+	// entitlements of the order.
+	Open(ctx context.Context, opening Opening) (*Dispute, error)
+	Get(ctx context.Context, id string) (*Dispute, error)
+	// FindOpen returns the open dispute of the order, or nil.
+	FindOpen(ctx context.Context, orderId string) (*Dispute, error)
+	// ListByOrder returns the disputes of the order, oldest first.
+	ListByOrder(ctx context.Context, orderId string) ([]Dispute, error)
+	// SubmitEvidence records the evidence sent to the bank, replacing the
+	// one sent before if any.
+	SubmitEvidence(ctx context.Context, id string, evidence string) (*Dispute, error)
+	// Resolve closes the dispute as won, which gives the entitlements back,
+	// or lost, which charges the order back.
+	Resolve(ctx context.Context, id string, state string) (*Dispute, error)
+	Stats(ctx context.Context, userId string) (*Stats, error)
+}
@@ -0,0 +1,67 @@
+package entitlement
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Entitlement grants a user access to a product bought with an order. A
+// refund of the order revokes it, an open dispute on the order suspends it.
+type Entitlement struct {
+	UserId      string     `json:"user_id"`
+	ProductId   string     `json:"product_id"`
+	OrderId     string     `json:"order_id"`
+	Name        string     `json:"name"`
+	PurchasedAt time.Time  `json:"purchased_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+}
+
+func (e Entitlement) Active() bool {
+	return e.RevokedAt == nil && e.SuspendedAt == nil
+}
+
+// ListOptions pages the active entitlements of a user, newest purchase first.
+type ListOptions struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+// Page is a single page of entitlements. NextCursor is nil on the last page.
+type Page struct {
+	Entitlements []Entitlement
+	NextCursor   *Cursor
+}
+
+// Cursor points at an entitlement by its keyset (purchased_at, product_id).
+type Cursor struct {
+	PurchasedAt time.Time `json:"t"`
+	ProductId   string    `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	j, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(j)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	j, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := new(Cursor)
+	if err := json.Unmarshal(j, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.ProductId == "" || cursor.PurchasedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
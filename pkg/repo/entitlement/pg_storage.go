@@ -0,0 +1,155 @@
+package entitlement
+
+import (
+	"context"
+	"database/sql"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+const entitlementColumns = "user_id, product_id, order_id, name, purchased_at, revoked_at, suspended_at"
+
+type PGEntitlementStorage struct {
+	db *sql.DB
+}
+
+func NewPGEntitlementStorage(url string) (*PGEntitlementStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGEntitlementStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGEntitlementStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGEntitlementStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGEntitlementStorage) List(ctx context.Context, userId string, opts ListOptions) (*Page, error) {
+	span, ctx := telemetry.StartSpan(ctx, "List", "PGEntitlementStorage")
+	defer span.End()
+
+	query := "SELECT " + entitlementColumns + " FROM entitlements WHERE user_id = $1 AND revoked_at IS NULL AND suspended_at IS NULL"
+	args := []any{userId}
+	if opts.Cursor != nil {
+		query += " AND (purchased_at, product_id) < ($2, $3)"
+		args = append(args, opts.Cursor.PurchasedAt, opts.Cursor.ProductId)
+	}
+
+	// one extra row tells whether there is another page
+	args = append(args, opts.Limit+1)
+	query += " ORDER BY purchased_at DESC, product_id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entitlements := []Entitlement{}
+	for rows.Next() {
+		entitlement, err := scanEntitlement(rows)
+		if err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, *entitlement)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		Entitlements: entitlements,
+	}
+	if len(entitlements) > opts.Limit {
+		page.Entitlements = entitlements[:opts.Limit]
+		last := page.Entitlements[opts.Limit-1]
+		page.NextCursor = &Cursor{PurchasedAt: last.PurchasedAt, ProductId: last.ProductId}
+	}
+
+	return page, nil
+}
+
+func (s PGEntitlementStorage) Get(ctx context.Context, userId string, productId string) (*Entitlement, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Get", "PGEntitlementStorage")
+	defer span.End()
+
+	query := "SELECT " + entitlementColumns + " FROM entitlements WHERE user_id = $1 AND product_id = $2"
+	row := s.db.QueryRowContext(ctx, query, userId, productId)
+
+	entitlement, err := scanEntitlement(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return entitlement, nil
+}
+
+func (s PGEntitlementStorage) Owned(ctx context.Context, userId string, productIds []string) ([]string, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Owned", "PGEntitlementStorage")
+	defer span.End()
+
+	if len(productIds) == 0 {
+		return []string{}, nil
+	}
+
+	query := "SELECT product_id FROM entitlements WHERE user_id = $1 AND product_id = ANY($2::uuid[]) AND revoked_at IS NULL"
+	rows, err := s.db.QueryContext(ctx, query, userId, pq.Array(productIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owned := []string{}
+	for rows.Next() {
+		var productId string
+		if err := rows.Scan(&productId); err != nil {
+			return nil, err
+		}
+		owned = append(owned, productId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return owned, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntitlement(row scanner) (*Entitlement, error) {
+	entitlement := new(Entitlement)
+	var revokedAt, suspendedAt sql.NullTime
+
+	err := row.Scan(&entitlement.UserId, &entitlement.ProductId, &entitlement.OrderId, &entitlement.Name, &entitlement.PurchasedAt, &revokedAt, &suspendedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		entitlement.RevokedAt = &revokedAt.Time
+	}
+	if suspendedAt.Valid {
+		entitlement.SuspendedAt = &suspendedAt.Time
+	}
+
+	return entitlement, nil
+}
@@ -0,0 +1,16 @@
+package entitlement
+
+import "context"
+
+// EntitlementStorage reads the entitlements granted and revoked by the order
+// transitions.
+type EntitlementStorage interface {
+	List(ctx context.Context, userId string, opts ListOptions) (*Page, error)
+	// Get returns the entitlement of the user for the product, active or
+	// not, or nil when the user never bought it.
+	Get(ctx context.Context, userId string, productId string) (*Entitlement, error)
+	// Owned returns the ids in productIds the user holds an entitlement
+	// for, suspended ones included since the products are not for sale to
+	// the user again.
+	Owned(ctx context.Context, userId string, productIds []string) ([]string, error)
+}
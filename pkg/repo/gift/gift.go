@@ -0,0 +1,63 @@
+package gift
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// the order is not paid yet
+	StatePending  = "pending"
+	StateActive   = "active"
+	StateRedeemed = "redeemed"
+	StateExpired  = "expired"
+	StateRefunded = "refunded"
+)
+
+var (
+	ErrNotFound        = errors.New("gift not found")
+	ErrAlreadyRedeemed = errors.New("gift already redeemed")
+	ErrExpired         = errors.New("gift expired")
+	ErrNotRedeemable   = errors.New("gift can't be redeemed")
+	ErrNotRecipient    = errors.New("gift is for another user")
+	ErrDisputed        = errors.New("gift order is disputed")
+)
+
+// Gift is a paid order whose products go to whoever redeems its code
+// instead of the buyer.
+type Gift struct {
+	Code            string
+	OrderId         string
+	BuyerId         string
+	RecipientEmail  string
+	RecipientUserId string
+	Message         string
+	State           string
+	ExpiresAt       *time.Time
+	RedeemedBy      string
+	RedeemedAt      *time.Time
+}
+
+// Expired tells whether an active gift is past its expiry, the state is
+// only updated when someone tries to redeem it.
+func (g Gift) Expired(now time.Time) bool {
+	return g.State == StateExpired || (g.State == StateActive && g.ExpiresAt != nil && !now.Before(*g.ExpiresAt))
+}
+
+// NewCode returns a random code in groups of four, e.g. ABCD-EFGH-IJKL-MNOP.
+func NewCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	raw := base32.StdEncoding.EncodeToString(b)
+	return raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16], nil
+}
+
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,176 @@
+package gift
+
+import (
+	"context"
+	"database/sql"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const giftColumns = "code, order_id, buyer_id, recipient_email, recipient_user_id, message, state, expires_at, redeemed_by, redeemed_at"
+
+type PGGiftStorage struct {
+	db *sql.DB
+}
+
+func NewPGGiftStorage(url string) (*PGGiftStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGGiftStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGGiftStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGGiftStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGGiftStorage) Redeem(ctx context.Context, code string, userId string) (*Gift, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Redeem", "PGGiftStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	g, err := scanGift(tx.QueryRowContext(ctx, "SELECT "+giftColumns+" FROM gifts WHERE code = $1 FOR UPDATE", code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	switch g.State {
+	case StateActive:
+	case StateRedeemed:
+		return nil, ErrAlreadyRedeemed
+	case StateExpired:
+		return nil, ErrExpired
+	default:
+		return nil, ErrNotRedeemable
+	}
+
+	if g.Expired(time.Now()) {
+		if _, err := tx.ExecContext(ctx, "UPDATE gifts SET state = $1 WHERE code = $2", StateExpired, code); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrExpired
+	}
+
+	if g.RecipientUserId != "" && g.RecipientUserId != userId {
+		return nil, ErrNotRecipient
+	}
+
+	// locking the order keeps a dispute from being opened on it meanwhile,
+	// the entitlements of a disputed order would be granted unsuspended
+	var disputed bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM disputes WHERE order_id = orders.id AND state IN ('opened', 'evidence_submitted'))
+		FROM orders WHERE id = $1 FOR UPDATE`, g.OrderId).Scan(&disputed)
+	if err != nil {
+		return nil, err
+	}
+	if disputed {
+		return nil, ErrDisputed
+	}
+
+	// the entitlements point at the gift order, so refunding it revokes
+	// them like for any other order
+	_, err = tx.ExecContext(ctx, `INSERT INTO entitlements (user_id, product_id, order_id, name)
+		SELECT $2, (item->>'id')::uuid, id, item->>'name' FROM orders, jsonb_array_elements(items) AS item WHERE id = $1
+		ON CONFLICT (user_id, product_id) DO UPDATE
+		SET order_id = EXCLUDED.order_id, name = EXCLUDED.name, purchased_at = now(), revoked_at = NULL, suspended_at = NULL
+		WHERE entitlements.revoked_at IS NOT NULL`, g.OrderId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE gifts SET state = $1, redeemed_by = $2, redeemed_at = $3 WHERE code = $4", StateRedeemed, userId, now, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	g.State = StateRedeemed
+	g.RedeemedBy = userId
+	g.RedeemedAt = &now
+
+	logging.FromContext(ctx).Debug("gift redeemed", zap.String("order_id", g.OrderId), zap.String("user_id", userId))
+	return g, nil
+}
+
+func (s PGGiftStorage) ListUnredeemed(ctx context.Context, limit int) ([]Gift, error) {
+	span, ctx := telemetry.StartSpan(ctx, "ListUnredeemed", "PGGiftStorage")
+	defer span.End()
+
+	query := "SELECT " + giftColumns + " FROM gifts WHERE state = $1 ORDER BY expires_at, code LIMIT $2"
+	rows, err := s.db.QueryContext(ctx, query, StateActive, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gifts := []Gift{}
+	for rows.Next() {
+		g, err := scanGift(rows)
+		if err != nil {
+			return nil, err
+		}
+		gifts = append(gifts, *g)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return gifts, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanGift(row scanner) (*Gift, error) {
+	g := new(Gift)
+	var recipientEmail, recipientUserId, message, redeemedBy sql.NullString
+	var expiresAt, redeemedAt sql.NullTime
+
+	err := row.Scan(&g.Code, &g.OrderId, &g.BuyerId, &recipientEmail, &recipientUserId, &message, &g.State, &expiresAt, &redeemedBy, &redeemedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	g.RecipientEmail = recipientEmail.String
+	g.RecipientUserId = recipientUserId.String
+	g.Message = message.String
+	g.RedeemedBy = redeemedBy.String
+	if expiresAt.Valid {
+		g.ExpiresAt = &expiresAt.Time
+	}
+	if redeemedAt.Valid {
+		g.RedeemedAt = &redeemedAt.Time
+	}
+
+	return g, nil
+}
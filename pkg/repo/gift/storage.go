@@ -0,0 +1,14 @@
+package gift
+
+import "context"
+
+// GiftStorage redeems and reports the gifts. Gifts are created, activated
+// and refunded by the order storage along with their order.
+type GiftStorage interface {
+	// Redeem grants the products of the gift to the user, it is refused
+	// while a dispute on the gift order is open.
+	Redeem(ctx context.Context, code string, userId string) (*Gift, error)
+	// ListUnredeemed returns the active gifts, the ones expiring first
+	// first.
+	ListUnredeemed(ctx context.Context, limit int) ([]Gift, error)
+}
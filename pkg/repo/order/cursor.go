@@ -0,0 +1,52 @@
+package order
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type CursorDirection string
+
+const (
+	CursorNext CursorDirection = "next"
+	CursorPrev CursorDirection = "prev"
+)
+
+// Cursor points at an order by its keyset (created_at, id). Direction tells
+// whether the page after or before that order is requested.
+type Cursor struct {
+	CreatedAt time.Time       `json:"t"`
+	Id        string          `json:"i"`
+	Direction CursorDirection `json:"d"`
+}
+
+func (c Cursor) Encode() string {
+	j, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(j)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	j, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := new(Cursor)
+	if err := json.Unmarshal(j, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.Id == "" || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.Direction != CursorNext && cursor.Direction != CursorPrev {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
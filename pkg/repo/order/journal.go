@@ -0,0 +1,98 @@
+package order
+
+import "orderservice/pkg/ledger"
+
+// captureEntry journals the payment of an order. The card leg, converted
+// from the currency it was charged in, and the store credit pay for the
+// revenue and the tax, less the discounts. The revenue comes from the
+// prices of the items rather than from what was paid, an order whose total
+// doesn't add up gives an entry ledger.Record refuses as unbalanced.
+func captureEntry(o *Order, p Payment) *ledger.Entry {
+	e := &ledger.Entry{OrderId: o.Id, Kind: ledger.KindCapture}
+
+	wallet := ledger.Cents(o.WalletAmount)
+	card := ledger.Cents(o.Total) - wallet
+	if p.Currency != "" && p.Currency != o.Currency {
+		charged := ledger.Cents(p.Amount)
+		e.Add(ledger.AccountCash, p.Currency, charged)
+		e.Add(ledger.AccountFXConversion, p.Currency, -charged)
+		e.Add(ledger.AccountFXConversion, o.Currency, card)
+	} else {
+		e.Add(ledger.AccountCash, o.Currency, card)
+	}
+	e.Add(ledger.AccountStoreCredit, o.Currency, wallet)
+
+	// inclusive taxes are part of the prices, exclusive ones were added on
+	// top of them
+	var sales, discounts, tax, inclusiveTax int64
+	for _, item := range o.Items {
+		sales += ledger.Cents(item.Price)
+	}
+	for _, d := range o.Discounts {
+		discounts += ledger.Cents(d.Amount)
+	}
+	for _, t := range o.Taxes {
+		tax += ledger.Cents(t.Amount)
+		if t.Inclusive {
+			inclusiveTax += ledger.Cents(t.Amount)
+		}
+	}
+	e.Add(ledger.AccountDiscounts, o.Currency, discounts)
+	e.Add(ledger.AccountTaxPayable, o.Currency, -tax)
+	e.Add(ledger.AccountRevenue, o.Currency, -(sales - inclusiveTax))
+
+	return e
+}
+
+// refundEntry journals the refund of an order by reversing its capture. A
+// refund paid as store credit leaves the money received where it is and
+// owes the total to the user instead.
+func refundEntry(o *Order, capture ledger.Entry, storeCredit bool) *ledger.Entry {
+	e := &ledger.Entry{OrderId: o.Id, Kind: ledger.KindRefund}
+	if !storeCredit {
+		e.Postings = capture.Reversed()
+		return e
+	}
+
+	var owed int64
+	for _, p := range capture.Reversed() {
+		switch p.Account {
+		case ledger.AccountCash, ledger.AccountFXConversion, ledger.AccountStoreCredit:
+			continue
+		}
+		e.Postings = append(e.Postings, p)
+		owed += p.Amount
+	}
+	e.Add(ledger.AccountStoreCredit, o.Currency, -owed)
+
+	return e
+}
+
+// chargebackEntry journals the payment taken back by the bank by reversing
+// the capture. The bank only takes back what the card paid, the store
+// credit spent on the order is not given back to the user and stays
+// revenue.
+func chargebackEntry(o *Order, capture ledger.Entry) *ledger.Entry {
+	e := &ledger.Entry{OrderId: o.Id, Kind: ledger.KindChargeback}
+
+	var kept int64
+	for _, p := range capture.Postings {
+		if p.Account == ledger.AccountStoreCredit {
+			kept += p.Amount
+		}
+	}
+	for _, p := range capture.Reversed() {
+		switch {
+		case p.Account == ledger.AccountStoreCredit:
+			continue
+		case p.Account == ledger.AccountRevenue && p.Currency == o.Currency:
+			p.Amount -= kept
+			kept = 0
+		}
+		e.Add(p.Account, p.Currency, p.Amount)
+	}
+	// left when the capture had no revenue to reverse
+	e.Add(ledger.AccountRevenue, o.Currency, -kept)
+
+	return e
+}
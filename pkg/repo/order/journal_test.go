@@ -0,0 +1,121 @@
+package order
+
+import (
+	"orderservice/pkg/ledger"
+	"testing"
+)
+
+func TestJournalEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   Order
+		payment Payment
+		// false for orders whose total doesn't add up
+		balanced bool
+	}{
+		{
+			name:     "card",
+			order:    Order{Total: 15, Items: []Item{{Price: 10}, {Price: 5}}},
+			payment:  Payment{Amount: 15, Currency: "EUR"},
+			balanced: true,
+		},
+		{
+			name: "discount and inclusive tax",
+			order: Order{Total: 18, Items: []Item{{Price: 20}},
+				Discounts: []Discount{{Amount: 2}},
+				Taxes:     []TaxLine{{Amount: 3.12, Inclusive: true}}},
+			payment:  Payment{Amount: 18, Currency: "EUR"},
+			balanced: true,
+		},
+		{
+			name:     "exclusive tax",
+			order:    Order{Total: 12.1, Items: []Item{{Price: 10}}, Taxes: []TaxLine{{Amount: 2.1}}},
+			payment:  Payment{Amount: 12.1, Currency: "EUR"},
+			balanced: true,
+		},
+		{
+			name:     "card and store credit",
+			order:    Order{Total: 15, WalletAmount: 5.5, Items: []Item{{Price: 15}}},
+			payment:  Payment{Amount: 9.5, Currency: "EUR"},
+			balanced: true,
+		},
+		{
+			name:     "store credit only",
+			order:    Order{Total: 15, WalletAmount: 15, Items: []Item{{Price: 15}}},
+			balanced: true,
+		},
+		{
+			name:     "card charged in another currency",
+			order:    Order{Total: 15, WalletAmount: 5, Items: []Item{{Price: 15}}},
+			payment:  Payment{Amount: 10.87, Currency: "USD"},
+			balanced: true,
+		},
+		{
+			name:     "total not matching the items",
+			order:    Order{Total: 12, Items: []Item{{Price: 10}}},
+			payment:  Payment{Amount: 12, Currency: "EUR"},
+			balanced: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := tt.order
+			o.Id, o.Currency = "order-1", "EUR"
+
+			capture := captureEntry(&o, tt.payment)
+			if capture.Balanced() != tt.balanced {
+				t.Fatalf("capture balanced = %v, want %v: %v", capture.Balanced(), tt.balanced, capture.Postings)
+			}
+			if !tt.balanced {
+				return
+			}
+
+			entries := map[string]*ledger.Entry{
+				"capture":                capture,
+				"refund":                 refundEntry(&o, *capture, false),
+				"refund as store credit": refundEntry(&o, *capture, true),
+				"chargeback":             chargebackEntry(&o, *capture),
+			}
+			for kind, e := range entries {
+				if !e.Balanced() {
+					t.Errorf("%s doesn't balance: %v", kind, e.Postings)
+				}
+				// an entry posts to an account once per currency
+				seen := map[ledger.Posting]bool{}
+				for _, p := range e.Postings {
+					key := ledger.Posting{Account: p.Account, Currency: p.Currency}
+					if seen[key] {
+						t.Errorf("%s posts to %s %s twice: %v", kind, p.Account, p.Currency, e.Postings)
+					}
+					seen[key] = true
+				}
+			}
+
+			// the bank takes back the card leg only
+			for _, p := range entries["chargeback"].Postings {
+				if p.Account == ledger.AccountStoreCredit {
+					t.Errorf("chargeback gives back store credit: %v", entries["chargeback"].Postings)
+				}
+			}
+			if got, want := sum(entries["chargeback"], ledger.AccountCash), -sum(capture, ledger.AccountCash); got != want {
+				t.Errorf("chargeback takes %d cash, want %d", got, want)
+			}
+
+			// store credit owed for the whole total
+			if got, want := sum(entries["refund as store credit"], ledger.AccountStoreCredit), -ledger.Cents(o.Total); got != want {
+				t.Errorf("refund as store credit owes %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func sum(e *ledger.Entry, account string) int64 {
+	var amount int64
+	for _, p := range e.Postings {
+		if p.Account == account {
+			amount += p.Amount
+		}
+	}
+	return amount
+}
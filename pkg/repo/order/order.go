@@ -1,16 +1,147 @@
 package order
 
+import (
+	"errors"
+	"orderservice/pkg/ledger"
+	"time"
+)
+
+var ErrGiftRedeemed = errors.New("gift order already redeemed")
+
+// ErrNotPayable is returned when an order is paid that is not ready or
+// approved anymore, completed by another path or given up meanwhile.
+var ErrNotPayable = errors.New("order is not waiting for its payment")
+
+// ErrAssessed is returned when the risk decision is recorded on an order
+// that already has one or was moved on meanwhile.
+var ErrAssessed = errors.New("order already assessed")
+
+// Currency is the one of the prices and totals of the new orders.
+const Currency = "EUR"
+
+// Payment is the card leg of the payment of an order, as charged by the
+// payment service. It is empty when store credit paid the whole order.
+type Payment struct {
+	Id       string
+	Amount   float32
+	Currency string
+}
+
 type Item struct {
 	Id    string  `json:"id"`
 	Name  string  `json:"name"`
 	Price float32 `json:"price"`
 }
 
+// Discount is a promotion applied to the order, Total is Subtotal less
+// the discounts.
+type Discount struct {
+	Code        string  `json:"code"`
+	Description string  `json:"description"`
+	Amount      float32 `json:"amount"`
+}
+
+// TaxLine is the tax charged on the order. Inclusive taxes are part of
+// Total already, exclusive ones were added to it.
+type TaxLine struct {
+	Country   string  `json:"country"`
+	Rate      float32 `json:"rate"`
+	Net       float32 `json:"net"`
+	Amount    float32 `json:"amount"`
+	Inclusive bool    `json:"inclusive"`
+}
+
+// TaxEvidence is what the customer location was derived from. Country is
+// the one taxed, set when the billing and IP countries agree.
+type TaxEvidence struct {
+	Country        string `json:"country,omitempty"`
+	BillingCountry string `json:"billing_country,omitempty"`
+	IPCountry      string `json:"ip_country,omitempty"`
+}
+
+// Gift sends the products of the order to a recipient, who redeems Code
+// once the order is paid. The code is valid for ValidFor after payment.
+type Gift struct {
+	Code            string        `json:"code"`
+	RecipientEmail  string        `json:"recipient_email,omitempty"`
+	RecipientUserId string        `json:"recipient_user_id,omitempty"`
+	Message         string        `json:"message,omitempty"`
+	State           string        `json:"state"`
+	ExpiresAt       *time.Time    `json:"expires_at,omitempty"`
+	RedeemedAt      *time.Time    `json:"redeemed_at,omitempty"`
+	ValidFor        time.Duration `json:"-"`
+}
+
+const (
+	StatusReady     = "ready"
+	StatusCompleted = "completed"
+	// an order held by the risk check until it is approved or rejected
+	StatusReview   = "review"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	// a completed order paid back, its entitlements are revoked
+	StatusRefunded = "refunded"
+	// an order whose payment failed after the checkout answered, reported
+	// by the payment service
+	StatusFailed = "failed"
+	// a completed order whose payment was taken back by the bank, its
+	// entitlements are revoked
+	StatusChargedBack = "charged_back"
+)
+
 type Order struct {
-	Id        string  `json:"id"`
-	Status    string  `json:"status"`
-	PaymentId *string `json:"payment_id,omitempty"`
-	UserId    string  `json:"user_id"`
-	Total     float32 `json:"total"`
-	Items     []Item  `json:"items"`
+	Id           string  `json:"id"`
+	Status       string  `json:"status"`
+	PaymentId    *string `json:"payment_id,omitempty"`
+	UserId       string  `json:"user_id"`
+	Subtotal     float32 `json:"subtotal"`
+	Total        float32 `json:"total"`
+	Currency     string  `json:"currency"`
+	WalletAmount float32 `json:"wallet_amount"`
+	// the card leg, in the currency of the charge: set before the card is
+	// charged and replaced by what was charged once the order completes
+	PaymentAmount   *float32    `json:"payment_amount,omitempty"`
+	PaymentCurrency string      `json:"payment_currency,omitempty"`
+	Items           []Item      `json:"items"`
+	Discounts       []Discount  `json:"discounts"`
+	Taxes           []TaxLine   `json:"taxes"`
+	TaxEvidence     TaxEvidence `json:"tax_evidence"`
+	Gift            *Gift       `json:"gift,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+	RiskDecision    *string     `json:"risk_decision,omitempty"`
+	RiskReasons     []string    `json:"risk_reasons,omitempty"`
+	// the card assessed by the risk check
+	CardFingerprint string `json:"-"`
+}
+
+// ChargedLeg tells whether a charge is the card leg recorded for the order
+// before its card was charged.
+func (o Order) ChargedLeg(amount float32, currency string) bool {
+	return o.PaymentAmount != nil && ledger.Cents(*o.PaymentAmount) == ledger.Cents(amount) && o.PaymentCurrency == currency
+}
+
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+// ListOptions narrows and pages the orders of a user. Orders are always
+// ordered by (created_at, id) so that a Cursor identifies a stable position.
+type ListOptions struct {
+	Limit         int
+	Cursor        *Cursor
+	Status        string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          SortDirection
+}
+
+// OrderPage is a single page of orders. NextCursor and PrevCursor are nil
+// when there is nothing to fetch in that direction.
+type OrderPage struct {
+	Orders     []Order
+	NextCursor *Cursor
+	PrevCursor *Cursor
 }
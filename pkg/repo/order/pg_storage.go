@@ -4,12 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"orderservice/pkg/events"
+	"orderservice/pkg/ledger"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/repo/gift"
+	"orderservice/pkg/telemetry"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
-	"go.elastic.co/apm/v2"
+	"go.uber.org/zap"
 )
 
+const orderColumns = "id, status, payment_id, user_id, total, currency, items, created_at, risk_decision, risk_reasons, subtotal, discounts, taxes, tax_country, billing_country, ip_country, wallet_amount, payment_amount, payment_currency, card_hmac, " +
+	"code, recipient_email, recipient_user_id, message, state, expires_at, redeemed_at"
+
+// orderTables joins the gift of the order, the gift columns don't clash
+// with the order ones so the conditions on orders need no table name
+const orderTables = "orders LEFT JOIN gifts ON gifts.order_id = orders.id"
+
 type PGOrderStorage struct {
 	db *sql.DB
 }
@@ -26,77 +42,493 @@ func NewPGOrderStorage(url string) (*PGOrderStorage, error) {
 	return s, nil
 }
 
-func (s PGOrderStorage) Create(ctx context.Context, userId string, total float32, items []Item) (*Order, error) {
-	span, ctx := apm.StartSpan(ctx, "Create", "PGOrderStorage")
+func (s PGOrderStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGOrderStorage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s PGOrderStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGOrderStorage) Create(ctx context.Context, draft Order) (*Order, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Create", "PGOrderStorage")
 	defer span.End()
 
-	itemsJson, err := json.Marshal(items)
+	order := draft
+	order.Id = uuid.NewString()
+	order.Status = StatusReady
+	if order.Currency == "" {
+		order.Currency = Currency
+	}
+	if order.Discounts == nil {
+		order.Discounts = []Discount{}
+	}
+	if order.Taxes == nil {
+		order.Taxes = []TaxLine{}
+	}
+
+	order.Subtotal = 0
+	for _, item := range order.Items {
+		order.Subtotal += item.Price
+	}
+
+	itemsJson, err := json.Marshal(order.Items)
+	if err != nil {
+		return nil, err
+	}
+	discountsJson, err := json.Marshal(order.Discounts)
+	if err != nil {
+		return nil, err
+	}
+	taxesJson, err := json.Marshal(order.Taxes)
 	if err != nil {
 		return nil, err
 	}
 
-	id := uuid.NewString()
-	_, err = s.db.Exec("INSERT INTO orders (id, status, user_id, total, items) VALUES ($1, $2, $3, $4, $5)", id, "ready", userId, total, itemsJson)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	order := new(Order)
-	order.Id = id
-	order.Status = "ready"
-	order.UserId = userId
-	order.Total = total
-	order.Items = items
+	evidence := order.TaxEvidence
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO orders (id, status, user_id, total, currency, items, subtotal, discounts, taxes, tax_country, billing_country, ip_country)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING created_at`,
+		order.Id, order.Status, order.UserId, order.Total, order.Currency, itemsJson, order.Subtotal, discountsJson, taxesJson,
+		nullString(evidence.Country), nullString(evidence.BillingCountry), nullString(evidence.IPCountry),
+	).Scan(&order.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
 
-	return order, nil
+	if order.Gift != nil {
+		g := *order.Gift
+		g.State = gift.StatePending
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO gifts (code, order_id, buyer_id, recipient_email, recipient_user_id, message, state, valid_for)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			g.Code, order.Id, order.UserId, nullString(g.RecipientEmail), nullString(g.RecipientUserId), nullString(g.Message), g.State, int64(g.ValidFor.Seconds()),
+		)
+		if err != nil {
+			return nil, err
+		}
+		order.Gift = &g
+	}
+
+	if err := publishStatus(ctx, tx, order.Id, order.Status); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Debug("order created", zap.String("order_id", order.Id), zap.Float32("total", order.Total))
+	return &order, nil
+}
+
+func (s PGOrderStorage) SetCharge(ctx context.Context, orderId string, amount float32, currency string) error {
+	span, ctx := telemetry.StartSpan(ctx, "SetCharge", "PGOrderStorage")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx, "UPDATE orders SET payment_amount=$1, payment_currency=$2 WHERE id=$3 AND status IN ($4, $5)",
+		amount, currency, orderId, StatusReady, StatusApproved)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return ErrNotPayable
+	}
+	return nil
 }
 
-func (s PGOrderStorage) Complete(ctx context.Context, orderId string, paymentId string) error {
-	span, ctx := apm.StartSpan(ctx, "Complete", "PGOrderStorage")
+func (s PGOrderStorage) SetWalletAmount(ctx context.Context, orderId string, amount float32) error {
+	span, ctx := telemetry.StartSpan(ctx, "SetWalletAmount", "PGOrderStorage")
 	defer span.End()
 
-	_, err := s.db.Exec("UPDATE orders SET status=$1, payment_id=$2 WHERE id=$3", "completed", paymentId, orderId)
-	return err
+	result, err := s.db.ExecContext(ctx, "UPDATE orders SET wallet_amount=$1 WHERE id=$2 AND status NOT IN ($3, $4, $5)",
+		amount, orderId, StatusCompleted, StatusRefunded, StatusChargedBack)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return ErrNotPayable
+	}
+	return nil
 }
 
-func (s PGOrderStorage) List(ctx context.Context, userId string) ([]Order, error) {
-	span, ctx := apm.StartSpan(ctx, "List", "PGOrderStorage")
+func (s PGOrderStorage) Complete(ctx context.Context, orderId string, payment Payment) error {
+	span, ctx := telemetry.StartSpan(ctx, "Complete", "PGOrderStorage")
 	defer span.End()
 
-	query := "SELECT id, status, payment_id, user_id, total, items FROM orders WHERE user_id = $1"
-	rows, err := s.db.Query(query, userId)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// only an order still waiting for its payment is completed, it is
+	// completed once whatever path gets there first
+	result, err := tx.ExecContext(ctx, "UPDATE orders SET status=$1, payment_id=$2, payment_amount=$3, payment_currency=$4 WHERE id=$5 AND status IN ($6, $7)",
+		StatusCompleted, nullString(payment.Id), sql.NullFloat64{Float64: float64(payment.Amount), Valid: payment.Id != ""}, nullString(payment.Currency), orderId,
+		StatusReady, StatusApproved)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return ErrNotPayable
+	}
+
+	// entitlements are granted with the completion so that a paid order
+	// always gives access to its products; a product bought again after a
+	// refund gets its entitlement back. Gift orders grant nothing to the
+	// buyer, their gift becomes redeemable instead
+	_, err = tx.ExecContext(ctx, `INSERT INTO entitlements (user_id, product_id, order_id, name)
+		SELECT user_id, (item->>'id')::uuid, id, item->>'name' FROM orders, jsonb_array_elements(items) AS item
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM gifts WHERE order_id = $1)
+		ON CONFLICT (user_id, product_id) DO UPDATE
+		SET order_id = EXCLUDED.order_id, name = EXCLUDED.name, purchased_at = now(), revoked_at = NULL, suspended_at = NULL
+		WHERE entitlements.revoked_at IS NOT NULL`, orderId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE gifts SET state = $1, expires_at = now() + valid_for * interval '1 second' WHERE order_id = $2 AND state = $3", gift.StateActive, orderId, gift.StatePending)
+	if err != nil {
+		return err
+	}
+
+	// the invoice number comes from a counter row per year, locked until
+	// the commit, so a rolled back completion doesn't leave a gap
+	year := time.Now().UTC().Year()
+	var sequence int
+	err = tx.QueryRowContext(ctx, `INSERT INTO invoice_sequences (year, last_number) VALUES ($1, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number`, year).Scan(&sequence)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO invoices (order_id, year, sequence) VALUES ($1, $2, $3)", orderId, year, sequence)
+	if err != nil {
+		return err
+	}
+
+	order, err := scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM "+orderTables+" WHERE id = $1", orderId))
+	if err != nil {
+		return err
+	}
+	if err := ledger.Record(ctx, tx, captureEntry(order, payment)); err != nil {
+		return err
+	}
+
+	if err := publishStatus(ctx, tx, orderId, StatusCompleted); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug("order completed", zap.String("order_id", orderId), zap.String("payment_id", payment.Id))
+	return nil
+}
+
+func (s PGOrderStorage) Refund(ctx context.Context, orderId string, storeCredit bool) (bool, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Refund", "PGOrderStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// a redeemed gift belongs to its recipient and is not paid back
+	var giftState sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT state FROM gifts WHERE order_id = $1 FOR UPDATE", orderId).Scan(&giftState)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if giftState.String == gift.StateRedeemed {
+		return false, ErrGiftRedeemed
+	}
+
+	// a disputed order is paid back by the bank if the dispute is lost
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET status=$1 WHERE id=$2 AND status=$3
+		AND NOT EXISTS (SELECT 1 FROM disputes WHERE order_id = $2 AND state IN ('opened', 'evidence_submitted'))`, StatusRefunded, orderId, StatusCompleted)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected != 1 {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE entitlements SET revoked_at=now() WHERE order_id=$1 AND revoked_at IS NULL", orderId)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE gifts SET state = $1 WHERE order_id = $2", gift.StateRefunded, orderId)
+	if err != nil {
+		return false, err
+	}
+
+	// orders completed before the ledger existed have no capture to reverse
+	captures, err := ledger.Find(ctx, tx, orderId, ledger.KindCapture)
+	if err != nil {
+		return false, err
+	}
+	if len(captures) > 0 {
+		order, err := scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM "+orderTables+" WHERE id = $1", orderId))
+		if err != nil {
+			return false, err
+		}
+		if err := ledger.Record(ctx, tx, refundEntry(order, captures[0], storeCredit)); err != nil {
+			return false, err
+		}
+	}
+
+	if err := publishStatus(ctx, tx, orderId, StatusRefunded); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	logging.FromContext(ctx).Debug("order refunded", zap.String("order_id", orderId))
+	return true, nil
+}
+
+func (s PGOrderStorage) List(ctx context.Context, userId string, opts ListOptions) (*OrderPage, error) {
+	span, ctx := telemetry.StartSpan(ctx, "List", "PGOrderStorage")
+	defer span.End()
+
+	sortDirection := opts.Sort
+	if sortDirection != SortAsc {
+		sortDirection = SortDesc
+	}
+
+	// a prev cursor walks the keyset backwards, so the query runs in the
+	// opposite direction and the result is reversed afterwards
+	backwards := opts.Cursor != nil && opts.Cursor.Direction == CursorPrev
+	queryAsc := (sortDirection == SortAsc) != backwards
+
+	conditions := []string{"user_id = $1"}
+	args := []any{userId}
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if opts.Status != "" {
+		conditions = append(conditions, "status = "+addArg(opts.Status))
+	}
+	if opts.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= "+addArg(*opts.CreatedAfter))
+	}
+	if opts.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < "+addArg(*opts.CreatedBefore))
+	}
+	if opts.Cursor != nil {
+		op := "<"
+		if queryAsc {
+			op = ">"
+		}
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s (%s, %s)", op, addArg(opts.Cursor.CreatedAt), addArg(opts.Cursor.Id)))
+	}
+
+	direction := "DESC"
+	if queryAsc {
+		direction = "ASC"
+	}
+
+	// one extra row tells whether there is another page in the query direction
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s ORDER BY created_at %s, id %s LIMIT %s",
+		orderColumns, orderTables, strings.Join(conditions, " AND "), direction, direction, addArg(opts.Limit+1),
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []Order
+	orders := []Order{}
 	for rows.Next() {
-		var id, status, paymentID, userID string
-		var total float64
-		var itemsJSON []byte
-
-		err := rows.Scan(&id, &status, &paymentID, &userID, &total, &itemsJSON)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		var items []Item
-		err = json.Unmarshal(itemsJSON, &items)
-		if err != nil {
-			return nil, err
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasMore := len(orders) > opts.Limit
+	if hasMore {
+		orders = orders[:opts.Limit]
+	}
+
+	if backwards {
+		for i, j := 0, len(orders)-1; i < j; i, j = i+1, j-1 {
+			orders[i], orders[j] = orders[j], orders[i]
 		}
+	}
+
+	page := &OrderPage{
+		Orders: orders,
+	}
+	if len(orders) == 0 {
+		return page, nil
+	}
 
-		order := Order{
-			Id:        id,
-			Status:    status,
-			PaymentId: &paymentID,
-			UserId:    userID,
-			Total:     float32(total),
-			Items:     items,
+	first, last := orders[0], orders[len(orders)-1]
+	if (backwards && hasMore) || (opts.Cursor != nil && !backwards) {
+		page.PrevCursor = &Cursor{CreatedAt: first.CreatedAt, Id: first.Id, Direction: CursorPrev}
+	}
+	if (!backwards && hasMore) || backwards {
+		page.NextCursor = &Cursor{CreatedAt: last.CreatedAt, Id: last.Id, Direction: CursorNext}
+	}
+
+	return page, nil
+}
+
+func (s PGOrderStorage) Get(ctx context.Context, orderId string) (*Order, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Get", "PGOrderStorage")
+	defer span.End()
+
+	query := "SELECT " + orderColumns + " FROM " + orderTables + " WHERE id = $1"
+	row := s.db.QueryRowContext(ctx, query, orderId)
+
+	order, err := scanOrder(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Return nil and nil error if no order found
+			return nil, nil
 		}
+		return nil, err
+	}
 
-		orders = append(orders, order)
+	return order, nil
+}
+
+func (s PGOrderStorage) SetRisk(ctx context.Context, orderId string, status string, decision string, reasons []string, cardFingerprint string) error {
+	span, ctx := telemetry.StartSpan(ctx, "SetRisk", "PGOrderStorage")
+	defer span.End()
+
+	reasonsJson, err := json.Marshal(reasons)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// a late or repeated assessment must not move the order back
+	result, err := tx.ExecContext(ctx, "UPDATE orders SET status=$1, risk_decision=$2, risk_reasons=$3, card_hmac=$4 WHERE id=$5 AND status=$6 AND risk_decision IS NULL",
+		status, decision, reasonsJson, nullString(cardFingerprint), orderId, StatusReady)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return ErrAssessed
+	}
+
+	if err := publishStatus(ctx, tx, orderId, status); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s PGOrderStorage) UpdateStatus(ctx context.Context, orderId string, from string, to string) (bool, error) {
+	span, ctx := telemetry.StartSpan(ctx, "UpdateStatus", "PGOrderStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE orders SET status=$1 WHERE id=$2 AND status=$3", to, orderId, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected != 1 {
+		return false, nil
+	}
+
+	if err := publishStatus(ctx, tx, orderId, to); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s PGOrderStorage) ListByStatus(ctx context.Context, status string, limit int) ([]Order, error) {
+	span, ctx := telemetry.StartSpan(ctx, "ListByStatus", "PGOrderStorage")
+	defer span.End()
+
+	query := "SELECT " + orderColumns + " FROM " + orderTables + " WHERE status = $1 ORDER BY created_at, id LIMIT $2"
+	rows, err := s.db.QueryContext(ctx, query, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -106,26 +538,90 @@ func (s PGOrderStorage) List(ctx context.Context, userId string) ([]Order, error
 	return orders, nil
 }
 
-func (s PGOrderStorage) Get(ctx context.Context, orderId string) (*Order, error) {
-	span, ctx := apm.StartSpan(ctx, "Get", "PGOrderStorage")
+func (s PGOrderStorage) ListCreated(ctx context.Context, from time.Time, to time.Time, after *Cursor, limit int) ([]Order, error) {
+	span, ctx := telemetry.StartSpan(ctx, "ListCreated", "PGOrderStorage")
 	defer span.End()
 
-	query := "SELECT id, status, payment_id, user_id, total, items FROM orders WHERE id = $1"
-	row := s.db.QueryRow(query, orderId)
+	query := "SELECT " + orderColumns + " FROM " + orderTables + " WHERE created_at >= $1 AND created_at < $2"
+	args := []any{from, to}
+	if after != nil {
+		query += " AND (created_at, id) > ($3, $4)"
+		args = append(args, after.CreatedAt, after.Id)
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d", len(args))
 
-	var id, status, paymentID, userID string
-	var total float64
-	var itemsJSON []byte
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
 
-	err := row.Scan(&id, &status, &paymentID, &userID, &total, &itemsJSON)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (s PGOrderStorage) FindApproved(ctx context.Context, userId string) (*Order, error) {
+	span, ctx := telemetry.StartSpan(ctx, "FindApproved", "PGOrderStorage")
+	defer span.End()
+
+	query := "SELECT " + orderColumns + " FROM " + orderTables + " WHERE user_id = $1 AND status = $2 ORDER BY created_at DESC, id DESC LIMIT 1"
+	row := s.db.QueryRowContext(ctx, query, userId, StatusApproved)
+
+	order, err := scanOrder(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			// Return nil and nil error if no order found
 			return nil, nil
 		}
 		return nil, err
 	}
 
+	return order, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrder(row scanner) (*Order, error) {
+	var id, status, userID string
+	var paymentID sql.NullString
+	var total float64
+	var currency string
+	var itemsJSON []byte
+	var createdAt time.Time
+	var riskDecision sql.NullString
+	var riskReasonsJSON []byte
+	var subtotal sql.NullFloat64
+	var discountsJSON []byte
+	var taxesJSON []byte
+	var taxCountry, billingCountry, ipCountry sql.NullString
+	var walletAmount float64
+	var paymentAmount sql.NullFloat64
+	var paymentCurrency sql.NullString
+	var cardFingerprint sql.NullString
+	var giftCode, giftRecipientEmail, giftRecipientUserId, giftMessage, giftState sql.NullString
+	var giftExpiresAt, giftRedeemedAt sql.NullTime
+
+	err := row.Scan(&id, &status, &paymentID, &userID, &total, &currency, &itemsJSON, &createdAt, &riskDecision, &riskReasonsJSON, &subtotal, &discountsJSON,
+		&taxesJSON, &taxCountry, &billingCountry, &ipCountry, &walletAmount, &paymentAmount, &paymentCurrency, &cardFingerprint,
+		&giftCode, &giftRecipientEmail, &giftRecipientUserId, &giftMessage, &giftState, &giftExpiresAt, &giftRedeemedAt)
+	if err != nil {
+		return nil, err
+	}
+
 	var items []Item
 	err = json.Unmarshal(itemsJSON, &items)
 	if err != nil {
@@ -133,13 +629,130 @@ func (s PGOrderStorage) Get(ctx context.Context, orderId string) (*Order, error)
 	}
 
 	order := &Order{
-		Id:        id,
-		Status:    status,
-		PaymentId: &paymentID,
-		UserId:    userID,
-		Total:     float32(total),
-		Items:     items,
+		Id:           id,
+		Status:       status,
+		UserId:       userID,
+		Subtotal:     float32(total),
+		Total:        float32(total),
+		Currency:     currency,
+		Items:        items,
+		WalletAmount: float32(walletAmount),
+		Discounts:    []Discount{},
+		Taxes:        []TaxLine{},
+		TaxEvidence: TaxEvidence{
+			Country:        taxCountry.String,
+			BillingCountry: billingCountry.String,
+			IPCountry:      ipCountry.String,
+		},
+		CreatedAt:       createdAt,
+		CardFingerprint: cardFingerprint.String,
+	}
+	// orders placed before discounts existed have no subtotal
+	if subtotal.Valid {
+		order.Subtotal = float32(subtotal.Float64)
+	}
+	if discountsJSON != nil {
+		if err := json.Unmarshal(discountsJSON, &order.Discounts); err != nil {
+			return nil, err
+		}
+	}
+	if taxesJSON != nil {
+		if err := json.Unmarshal(taxesJSON, &order.Taxes); err != nil {
+			return nil, err
+		}
+	}
+	if giftCode.Valid {
+		order.Gift = &Gift{
+			Code:            giftCode.String,
+			RecipientEmail:  giftRecipientEmail.String,
+			RecipientUserId: giftRecipientUserId.String,
+			Message:         giftMessage.String,
+			State:           giftState.String,
+		}
+		if giftExpiresAt.Valid {
+			order.Gift.ExpiresAt = &giftExpiresAt.Time
+		}
+		if giftRedeemedAt.Valid {
+			order.Gift.RedeemedAt = &giftRedeemedAt.Time
+		}
+	}
+	if paymentAmount.Valid {
+		amount := float32(paymentAmount.Float64)
+		order.PaymentAmount = &amount
+		order.PaymentCurrency = paymentCurrency.String
+	}
+	if paymentID.Valid {
+		order.PaymentId = &paymentID.String
+	}
+	if riskDecision.Valid {
+		order.RiskDecision = &riskDecision.String
+	}
+	if riskReasonsJSON != nil {
+		if err := json.Unmarshal(riskReasonsJSON, &order.RiskReasons); err != nil {
+			return nil, err
+		}
 	}
 
 	return order, nil
 }
+
+// ChargeBack moves a completed order to charged_back within tx, revokes its
+// entitlements, those of a redeemed gift too, and journals the reversal of
+// the capture. It reports whether the order was completed.
+func ChargeBack(ctx context.Context, tx *sql.Tx, orderId string) (bool, error) {
+	result, err := tx.ExecContext(ctx, "UPDATE orders SET status=$1 WHERE id=$2 AND status=$3", StatusChargedBack, orderId, StatusCompleted)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected != 1 {
+		return false, nil
+	}
+
+	// unlike a refund, the money is gone whether the gift was redeemed or
+	// not; the entitlements of its recipient point at the order as well
+	_, err = tx.ExecContext(ctx, "UPDATE entitlements SET revoked_at=now() WHERE order_id=$1 AND revoked_at IS NULL", orderId)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE gifts SET state = $1 WHERE order_id = $2 AND state <> $3", gift.StateRefunded, orderId, gift.StateRedeemed)
+	if err != nil {
+		return false, err
+	}
+
+	captures, err := ledger.Find(ctx, tx, orderId, ledger.KindCapture)
+	if err != nil {
+		return false, err
+	}
+	if len(captures) > 0 {
+		order, err := scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM "+orderTables+" WHERE id = $1", orderId))
+		if err != nil {
+			return false, err
+		}
+		if err := ledger.Record(ctx, tx, chargebackEntry(order, captures[0])); err != nil {
+			return false, err
+		}
+	}
+
+	if err := publishStatus(ctx, tx, orderId, StatusChargedBack); err != nil {
+		return false, err
+	}
+
+	logging.FromContext(ctx).Debug("order charged back", zap.String("order_id", orderId))
+	return true, nil
+}
+
+// publishStatus tells the subscribers of the order about its new status
+// once tx commits.
+func publishStatus(ctx context.Context, tx *sql.Tx, orderId string, status string) error {
+	return events.Publish(ctx, tx, events.Event{OrderId: orderId, Type: events.TypeStatus, State: status})
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -1,10 +1,46 @@
 package order
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type OrderStorage interface {
-	Create(ctx context.Context, userId string, total float32, items []Item) (*Order, error)
-	Complete(ctx context.Context, orderId string, paymentId string) error
-	List(ctx context.Context, userId string) ([]Order, error)
+	// Create stores a new ready order from draft, which holds the user,
+	// items, total and the discount and tax lines. Subtotal is computed
+	// from the items.
+	Create(ctx context.Context, draft Order) (*Order, error)
+	// SetCharge records the card leg of a ready or approved order before
+	// its card is charged, other orders fail with ErrNotPayable.
+	SetCharge(ctx context.Context, orderId string, amount float32, currency string) error
+	// SetWalletAmount records the store credit spent on an order not paid
+	// yet, zero once it is given back. A paid order keeps how it was paid
+	// and fails with ErrNotPayable.
+	SetWalletAmount(ctx context.Context, orderId string, amount float32) error
+	// Complete marks a ready or approved order as paid, grants the
+	// entitlements to its products, numbers its invoice and journals the
+	// capture in the ledger. Other orders fail with ErrNotPayable.
+	Complete(ctx context.Context, orderId string, payment Payment) error
+	// Refund moves a completed order to refunded, revokes its entitlements
+	// and journals the refund, paid as store credit or not, in the ledger.
+	// It reports whether the order was completed and not disputed. Gift
+	// orders whose gift was redeemed fail with ErrGiftRedeemed.
+	Refund(ctx context.Context, orderId string, storeCredit bool) (bool, error)
+	List(ctx context.Context, userId string, opts ListOptions) (*OrderPage, error)
 	Get(ctx context.Context, orderId string) (*Order, error)
+	// SetRisk records the risk decision on the order along with the card it
+	// was made for, and moves it to status. It fails with ErrAssessed unless
+	// the order is ready and was not assessed yet.
+	SetRisk(ctx context.Context, orderId string, status string, decision string, reasons []string, cardFingerprint string) error
+	// UpdateStatus moves the order to status only if it is currently in
+	// from, and reports whether it did.
+	UpdateStatus(ctx context.Context, orderId string, from string, to string) (bool, error)
+	ListByStatus(ctx context.Context, status string, limit int) ([]Order, error)
+	// ListCreated pages the orders of every user created in [from, to),
+	// oldest first, starting after the order after points at.
+	ListCreated(ctx context.Context, from time.Time, to time.Time, after *Cursor, limit int) ([]Order, error)
+	// FindApproved returns the latest order of the user approved after a
+	// risk review and not paid yet. It is only paid with the card that was
+	// reviewed, see Order.CardFingerprint.
+	FindApproved(ctx context.Context, userId string) (*Order, error)
 }
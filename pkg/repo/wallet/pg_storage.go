@@ -0,0 +1,376 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+const entryColumns = "id, user_id, kind, amount, order_id, reason, expires_at, created_at"
+
+type PGWalletStorage struct {
+	db *sql.DB
+}
+
+func NewPGWalletStorage(url string) (*PGWalletStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGWalletStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGWalletStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGWalletStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGWalletStorage) Balance(ctx context.Context, userId string) (*Balance, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Balance", "PGWalletStorage")
+	defer span.End()
+
+	if err := expire(ctx, s.db, userId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT remaining, expires_at FROM wallet_entries WHERE user_id = $1 AND kind = $2 AND remaining > 0 ORDER BY expires_at NULLS LAST, created_at"
+	rows, err := s.db.QueryContext(ctx, query, userId, KindCredit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var amount float64
+	balance := &Balance{
+		Expiring: []Expiring{},
+	}
+	for rows.Next() {
+		var remaining float64
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&remaining, &expiresAt); err != nil {
+			return nil, err
+		}
+		amount += remaining
+		if expiresAt.Valid {
+			balance.Expiring = append(balance.Expiring, Expiring{Amount: float32(remaining), ExpiresAt: expiresAt.Time})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	balance.Amount = float32(cents(amount))
+	return balance, nil
+}
+
+func (s PGWalletStorage) List(ctx context.Context, userId string, opts ListOptions) (*Page, error) {
+	span, ctx := telemetry.StartSpan(ctx, "List", "PGWalletStorage")
+	defer span.End()
+
+	if err := expire(ctx, s.db, userId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + entryColumns + " FROM wallet_entries WHERE user_id = $1"
+	args := []any{userId}
+	if opts.Cursor != nil {
+		query += " AND (created_at, id) < ($2, $3)"
+		args = append(args, opts.Cursor.CreatedAt, opts.Cursor.Id)
+	}
+
+	// one extra row tells whether there is another page
+	args = append(args, opts.Limit+1)
+	query += " ORDER BY created_at DESC, id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		Entries: entries,
+	}
+	if len(entries) > opts.Limit {
+		page.Entries = entries[:opts.Limit]
+		last := page.Entries[opts.Limit-1]
+		page.NextCursor = &Cursor{CreatedAt: last.CreatedAt, Id: last.Id}
+	}
+
+	return page, nil
+}
+
+func (s PGWalletStorage) Credit(ctx context.Context, credit Credit) (*Entry, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Credit", "PGWalletStorage")
+	defer span.End()
+
+	amount := cents(float64(credit.Amount))
+	query := `INSERT INTO wallet_entries (user_id, kind, amount, remaining, order_id, reason, expires_at)
+		VALUES ($1, $2, $3, $3, $4, $5, $6) RETURNING ` + entryColumns
+	row := s.db.QueryRowContext(ctx, query, credit.UserId, KindCredit, amount, credit.OrderId, credit.Reason, credit.ExpiresAt)
+
+	entry, err := scanEntry(row)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Debug("wallet credited", zap.String("user_id", credit.UserId), zap.Float64("amount", amount))
+	return entry, nil
+}
+
+func (s PGWalletStorage) Debit(ctx context.Context, userId string, orderId string, amount float32) (float32, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Debit", "PGWalletStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := expire(ctx, tx, userId); err != nil {
+		return 0, err
+	}
+
+	// locking the credits of the user serializes the debits of the user,
+	// so the same credit is never spent twice
+	query := "SELECT id, remaining FROM wallet_entries WHERE user_id = $1 AND kind = $2 AND remaining > 0 ORDER BY expires_at NULLS LAST, created_at, id FOR UPDATE"
+	rows, err := tx.QueryContext(ctx, query, userId, KindCredit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	lots := []lot{}
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.id, &l.remaining); err != nil {
+			return 0, err
+		}
+		lots = append(lots, l)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	var debited float64
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM wallet_entries WHERE order_id = $1 AND kind = $2 AND reversed_at IS NULL", orderId, KindDebit).Scan(&debited)
+	if err != nil {
+		return 0, err
+	}
+	if debited > 0 {
+		return float32(debited), tx.Commit()
+	}
+
+	total, takes := allocate(lots, float64(amount))
+	if total <= 0 {
+		return 0, tx.Commit()
+	}
+
+	var debitId string
+	err = tx.QueryRowContext(ctx, "INSERT INTO wallet_entries (user_id, kind, amount, order_id, reason) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		userId, KindDebit, total, orderId, "order payment").Scan(&debitId)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, take := range takes {
+		if _, err := tx.ExecContext(ctx, "UPDATE wallet_entries SET remaining = remaining - $1 WHERE id = $2", take.remaining, take.id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO wallet_allocations (debit_id, credit_id, amount) VALUES ($1, $2, $3)", debitId, take.id, take.remaining); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	logging.FromContext(ctx).Debug("wallet debited", zap.String("user_id", userId), zap.String("order_id", orderId), zap.Float64("amount", total))
+	return float32(total), nil
+}
+
+func (s PGWalletStorage) Reverse(ctx context.Context, orderId string, reason string) (float32, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Reverse", "PGWalletStorage")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "UPDATE wallet_entries SET reversed_at = now() WHERE order_id = $1 AND kind = $2 AND reversed_at IS NULL RETURNING id, user_id, amount", orderId, KindDebit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	type debit struct {
+		id     string
+		userId string
+		amount float64
+	}
+	debits := []debit{}
+	for rows.Next() {
+		var d debit
+		if err := rows.Scan(&d.id, &d.userId, &d.amount); err != nil {
+			return 0, err
+		}
+		debits = append(debits, d)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	// the amount goes back to the credits it came from, keeping their
+	// expiry; one that expired in the meantime expires again on next access
+	var reversed float64
+	for _, d := range debits {
+		_, err := tx.ExecContext(ctx, `UPDATE wallet_entries AS credit SET remaining = credit.remaining + allocation.amount
+			FROM wallet_allocations AS allocation WHERE allocation.debit_id = $1 AND credit.id = allocation.credit_id`, d.id)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO wallet_entries (user_id, kind, amount, order_id, reason) VALUES ($1, $2, $3, $4, $5)",
+			d.userId, KindReversal, d.amount, orderId, reason)
+		if err != nil {
+			return 0, err
+		}
+		reversed += d.amount
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if reversed > 0 {
+		logging.FromContext(ctx).Debug("wallet debit reversed", zap.String("order_id", orderId), zap.Float64("amount", reversed))
+	}
+	return float32(reversed), nil
+}
+
+func (s PGWalletStorage) Reversed(ctx context.Context, orderId string) (float32, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Reversed", "PGWalletStorage")
+	defer span.End()
+
+	var reversed float64
+	err := s.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM wallet_entries WHERE order_id = $1 AND kind = $2", orderId, KindReversal).Scan(&reversed)
+	if err != nil {
+		return 0, err
+	}
+
+	return float32(reversed), nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// expire records an expiration entry for what is left of the expired
+// credits of the user, and empties them.
+func expire(ctx context.Context, db execer, userId string) error {
+	_, err := db.ExecContext(ctx, `WITH expired AS (
+			UPDATE wallet_entries AS credit SET remaining = 0
+			FROM (SELECT id, remaining FROM wallet_entries WHERE user_id = $1 AND kind = $2 AND remaining > 0 AND expires_at <= now() FOR UPDATE) AS lot
+			WHERE credit.id = lot.id
+			RETURNING credit.id, credit.user_id, lot.remaining
+		)
+		INSERT INTO wallet_entries (user_id, kind, amount, credit_id, reason)
+		SELECT user_id, $3, remaining, id, 'credit expired' FROM expired`, userId, KindCredit, KindExpiration)
+	return err
+}
+
+// lot is what is left of a credit.
+type lot struct {
+	id        string
+	remaining float64
+}
+
+// allocate spends up to amount from the lots, in their order, and returns
+// the total spent and what is taken from each lot.
+func allocate(lots []lot, amount float64) (float64, []lot) {
+	var available float64
+	for _, l := range lots {
+		available += l.remaining
+	}
+
+	left := math.Min(cents(amount), cents(available))
+	if left <= 0 {
+		return 0, nil
+	}
+	total := left
+
+	takes := []lot{}
+	for _, l := range lots {
+		if left <= 0 {
+			break
+		}
+		take := math.Min(l.remaining, left)
+		takes = append(takes, lot{id: l.id, remaining: take})
+		left = cents(left - take)
+	}
+	return total, takes
+}
+
+// cents rounds an amount to the cent, float32 amounts don't convert to
+// float64 exactly.
+func cents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row scanner) (*Entry, error) {
+	entry := new(Entry)
+	var amount float64
+	var orderId sql.NullString
+	var expiresAt sql.NullTime
+
+	err := row.Scan(&entry.Id, &entry.UserId, &entry.Kind, &amount, &orderId, &entry.Reason, &expiresAt, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Amount = float32(amount)
+	if orderId.Valid {
+		entry.OrderId = &orderId.String
+	}
+	if expiresAt.Valid {
+		entry.ExpiresAt = &expiresAt.Time
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,46 @@
+package wallet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	lots := []lot{{"expiring", 5}, {"later", 10}, {"never", 2.5}}
+
+	tests := []struct {
+		name      string
+		lots      []lot
+		amount    float64
+		wantTotal float64
+		wantTakes []lot
+	}{
+		{"first lot covers it", lots, 3, 3, []lot{{"expiring", 3}}},
+		{"first lot spent exactly", lots, 5, 5, []lot{{"expiring", 5}}},
+		{"spills over the next lots", lots, 16.25, 16.25, []lot{{"expiring", 5}, {"later", 10}, {"never", 1.25}}},
+		{"more than the balance", lots, 40, 17.5, []lot{{"expiring", 5}, {"later", 10}, {"never", 2.5}}},
+		{"amount rounded to the cent", lots, float64(float32(5.1)), 5.1, []lot{{"expiring", 5}, {"later", 0.1}}},
+		{"nothing to pay", lots, 0, 0, nil},
+		{"no credit", nil, 10, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, takes := allocate(tt.lots, tt.amount)
+			if total != tt.wantTotal {
+				t.Errorf("allocate() total = %v, want %v", total, tt.wantTotal)
+			}
+			if !reflect.DeepEqual(takes, tt.wantTakes) {
+				t.Errorf("allocate() takes = %v, want %v", takes, tt.wantTakes)
+			}
+
+			var taken float64
+			for _, take := range takes {
+				taken += take.remaining
+			}
+			if cents(taken) != total {
+				t.Errorf("allocate() took %v for a total of %v", taken, total)
+			}
+		})
+	}
+}
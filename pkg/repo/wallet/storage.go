@@ -0,0 +1,22 @@
+package wallet
+
+import "context"
+
+type WalletStorage interface {
+	// Balance returns the spendable store credit of the user.
+	Balance(ctx context.Context, userId string) (*Balance, error)
+	// List pages the ledger of the user.
+	List(ctx context.Context, userId string, opts ListOptions) (*Page, error)
+	// Credit adds store credit to the wallet of a user.
+	Credit(ctx context.Context, credit Credit) (*Entry, error)
+	// Debit spends up to amount of the balance of the user on the order,
+	// credits expiring first being spent first, and returns the amount
+	// spent, which the order storage records on the order. Debiting an
+	// order again returns what it was debited already.
+	Debit(ctx context.Context, userId string, orderId string, amount float32) (float32, error)
+	// Reverse gives back what was debited for the order to the credits it
+	// was spent from, and returns the amount given back.
+	Reverse(ctx context.Context, orderId string, reason string) (float32, error)
+	// Reversed returns the amount given back for the order so far.
+	Reversed(ctx context.Context, orderId string) (float32, error)
+}
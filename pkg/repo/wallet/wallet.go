@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	// store credit given to the user, spendable until it expires
+	KindCredit = "credit"
+	// store credit spent on an order
+	KindDebit = "debit"
+	// a debit given back because the order was not paid
+	KindReversal = "reversal"
+	// the part of a credit left unspent when it expired
+	KindExpiration = "expiration"
+)
+
+// Entry is a line of the wallet ledger of a user. Amount is always positive,
+// Kind tells whether it adds to the balance or takes from it.
+type Entry struct {
+	Id        string     `json:"id"`
+	UserId    string     `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Amount    float32    `json:"amount"`
+	OrderId   *string    `json:"order_id,omitempty"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Credit is store credit to give to a user, like a refund or a goodwill
+// gesture. It never expires when ExpiresAt is nil.
+type Credit struct {
+	UserId    string
+	Amount    float32
+	Reason    string
+	OrderId   *string
+	ExpiresAt *time.Time
+}
+
+// Expiring is a part of the balance that expires at ExpiresAt unless it is
+// spent before.
+type Expiring struct {
+	Amount    float32
+	ExpiresAt time.Time
+}
+
+type Balance struct {
+	Amount   float32
+	Expiring []Expiring
+}
+
+// ListOptions pages the ledger of a user, newest entry first.
+type ListOptions struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+// Page is a single page of entries. NextCursor is nil on the last page.
+type Page struct {
+	Entries    []Entry
+	NextCursor *Cursor
+}
+
+// Cursor points at an entry by its keyset (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	Id        string    `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	j, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(j)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	j, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := new(Cursor)
+	if err := json.Unmarshal(j, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.Id == "" || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
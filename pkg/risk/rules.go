@@ -0,0 +1,180 @@
+package risk
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	RuleMaxOrdersPerUser = "max_orders_per_user"
+	RuleMaxCardsPerUser  = "max_cards_per_user"
+	RuleAmountAbove      = "amount_above"
+	RuleBlockedBIN       = "blocked_bin"
+	RuleBlockedUser      = "blocked_user"
+	RuleMaxOpenDisputes  = "max_open_disputes"
+	RuleMaxLostDisputes  = "max_lost_disputes"
+)
+
+//go:embed default_rules.json
+var defaultRules []byte
+
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// Rule is a single declarative check. Which fields are used depends on
+// Type.
+type Rule struct {
+	Type   string   `json:"type"`
+	Action Decision `json:"action"`
+	Window Duration `json:"window"`
+	Limit  int      `json:"limit"`
+	Amount float32  `json:"amount"`
+	Values []string `json:"values"`
+}
+
+type RuleSet struct {
+	Rules []Rule `json:"rules"`
+}
+
+// LoadRules reads a rule set from path, or the bundled default rules when
+// path is empty.
+func LoadRules(path string) (*RuleSet, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	set := new(RuleSet)
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range set.Rules {
+		if rule.Action != Review && rule.Action != Deny {
+			return nil, fmt.Errorf("rule %s: unknown action %q", rule.Type, rule.Action)
+		}
+		switch rule.Type {
+		case RuleMaxOrdersPerUser, RuleMaxCardsPerUser:
+			if rule.Limit < 1 || rule.Window.Duration <= 0 {
+				return nil, fmt.Errorf("rule %s: needs limit and window", rule.Type)
+			}
+		case RuleMaxOpenDisputes, RuleMaxLostDisputes:
+			if rule.Limit < 0 {
+				return nil, fmt.Errorf("rule %s: limit can't be negative", rule.Type)
+			}
+		case RuleAmountAbove, RuleBlockedBIN, RuleBlockedUser:
+		default:
+			return nil, fmt.Errorf("unknown rule type %q", rule.Type)
+		}
+	}
+	return set, nil
+}
+
+// RuleEngine evaluates a rule set, using the velocity store for the rules
+// counting past checkouts. Every evaluated checkout is recorded, so denied
+// attempts count towards the velocity limits too.
+type RuleEngine struct {
+	rules    *RuleSet
+	velocity VelocityStore
+}
+
+func NewRuleEngine(rules *RuleSet, velocity VelocityStore) *RuleEngine {
+	return &RuleEngine{
+		rules:    rules,
+		velocity: velocity,
+	}
+}
+
+func (e RuleEngine) Evaluate(ctx context.Context, input Input) (*Assessment, error) {
+	if err := e.velocity.Record(ctx, input.UserId, input.OrderId, input.CardFingerprint); err != nil {
+		return nil, err
+	}
+
+	assessment := &Assessment{
+		Decision: Allow,
+		Reasons:  []string{},
+	}
+	for _, rule := range e.rules.Rules {
+		reason, err := e.check(ctx, rule, input)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			continue
+		}
+
+		assessment.Reasons = append(assessment.Reasons, reason)
+		if rule.Action.severity() > assessment.Decision.severity() {
+			assessment.Decision = rule.Action
+		}
+	}
+	return assessment, nil
+}
+
+// check returns why the rule matched or an empty string.
+func (e RuleEngine) check(ctx context.Context, rule Rule, input Input) (string, error) {
+	switch rule.Type {
+	case RuleMaxOrdersPerUser:
+		count, err := e.velocity.Orders(ctx, input.UserId, rule.Window.Duration)
+		if err != nil || count <= rule.Limit {
+			return "", err
+		}
+		return fmt.Sprintf("%d orders in %s", count, rule.Window.Duration), nil
+	case RuleMaxCardsPerUser:
+		count, err := e.velocity.Cards(ctx, input.UserId, rule.Window.Duration)
+		if err != nil || count <= rule.Limit {
+			return "", err
+		}
+		return fmt.Sprintf("%d cards in %s", count, rule.Window.Duration), nil
+	case RuleAmountAbove:
+		if input.Amount <= rule.Amount {
+			return "", nil
+		}
+		return fmt.Sprintf("amount above %.2f", rule.Amount), nil
+	case RuleBlockedBIN:
+		for _, bin := range rule.Values {
+			if strings.HasPrefix(input.CardNumber, bin) {
+				return "blocked bin " + bin, nil
+			}
+		}
+	case RuleBlockedUser:
+		for _, userId := range rule.Values {
+			if userId == input.UserId {
+				return "blocked user", nil
+			}
+		}
+	case RuleMaxOpenDisputes:
+		if input.OpenDisputes > rule.Limit {
+			return fmt.Sprintf("%d open disputes", input.OpenDisputes), nil
+		}
+	case RuleMaxLostDisputes:
+		if input.LostDisputes > rule.Limit {
+			return fmt.Sprintf("%d lost disputes", input.LostDisputes), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,82 @@
+package risk
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// VelocityStore counts the recent checkouts and cards of a user.
+type VelocityStore interface {
+	Record(ctx context.Context, userId, orderId, cardFingerprint string) error
+	Orders(ctx context.Context, userId string, window time.Duration) (int, error)
+	Cards(ctx context.Context, userId string, window time.Duration) (int, error)
+}
+
+// RedisVelocityStore keeps a sorted set per user and counter, scored by
+// the time of the last hit. Entries older than retention are trimmed on
+// every write.
+type RedisVelocityStore struct {
+	redisClient *redis.Client
+	retention   time.Duration
+}
+
+func NewRedisVelocityStore(url string, retention time.Duration) (*RedisVelocityStore, error) {
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &RedisVelocityStore{
+		redisClient: redis.NewClient(opt),
+		retention:   retention,
+	}
+	return store, nil
+}
+
+func (r RedisVelocityStore) Record(ctx context.Context, userId, orderId, cardFingerprint string) error {
+	now := time.Now()
+	score := float64(now.UnixMilli())
+	min := "(" + formatMillis(now.Add(-r.retention))
+
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, member := range map[string]string{ordersKey(userId): orderId, cardsKey(userId): cardFingerprint} {
+			pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: member})
+			pipe.ZRemRangeByScore(ctx, key, "-inf", min)
+			pipe.Expire(ctx, key, r.retention)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r RedisVelocityStore) Orders(ctx context.Context, userId string, window time.Duration) (int, error) {
+	return r.count(ctx, ordersKey(userId), window)
+}
+
+func (r RedisVelocityStore) Cards(ctx context.Context, userId string, window time.Duration) (int, error) {
+	return r.count(ctx, cardsKey(userId), window)
+}
+
+func (r RedisVelocityStore) Close() error {
+	return r.redisClient.Close()
+}
+
+func (r RedisVelocityStore) count(ctx context.Context, key string, window time.Duration) (int, error) {
+	count, err := r.redisClient.ZCount(ctx, key, formatMillis(time.Now().Add(-window)), "+inf").Result()
+	return int(count), err
+}
+
+func ordersKey(userId string) string {
+	return "risk:orders:" + userId
+}
+
+func cardsKey(userId string) string {
+	return "risk:cards:" + userId
+}
+
+func formatMillis(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	userIdHeader     = "x-user-id"
+	userIdContextKey = "user_id"
+)
+
+type JWTAuthConfig struct {
+	// Skipper defines a function to skip the middleware, used to limit it
+	// to the route groups that need an authenticated user.
+	Skipper middleware.Skipper
+
+	Keys        KeySet
+	Issuer      string
+	Audience    string
+	UserIdClaim string
+	Leeway      time.Duration
+}
+
+// JWTAuth validates the bearer token of the request and makes sure the
+// x-user-id header names the user the token was issued for, setting it
+// when the client left it out. The header is kept because the generated
+// handlers read the user id from it.
+func JWTAuth(config JWTAuthConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.UserIdClaim == "" {
+		config.UserIdClaim = "sub"
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(config.Audience))
+	}
+	parser := jwt.NewParser(opts...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			auth := c.Request().Header.Get(echo.HeaderAuthorization)
+			tokenString, ok := strings.CutPrefix(auth, "Bearer ")
+			if !ok || tokenString == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing bearer token")
+			}
+
+			claims := jwt.MapClaims{}
+			_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+				kid, _ := token.Header["kid"].(string)
+				return config.Keys.Key(c.Request().Context(), kid)
+			})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid bearer token")
+			}
+
+			userId, _ := claims[config.UserIdClaim].(string)
+			if userId == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token has no user id")
+			}
+
+			headerUserId := c.Request().Header.Get(userIdHeader)
+			if headerUserId == "" {
+				c.Request().Header.Set(userIdHeader, userId)
+			} else if headerUserId != userId {
+				return echo.NewHTTPError(http.StatusForbidden, "x-user-id does not match token")
+			}
+
+			c.Set(userIdContextKey, userId)
+			return next(c)
+		}
+	}
+}
+
+// PathPrefixSkipper skips every request whose path is outside the given
+// prefixes.
+func PathPrefixSkipper(prefixes []string) middleware.Skipper {
+	return func(c echo.Context) bool {
+		path := c.Request().URL.Path
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(path, prefix) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,440 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"orderservice/pkg/checkout"
+	"orderservice/pkg/client/payment"
+	"orderservice/pkg/events"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// heartbeatInterval keeps idle event streams from being closed by proxies.
+const heartbeatInterval = 15 * time.Second
+
+// pay charges an approved or ready order and completes it. Store credit
+// pays first and the card pays the rest; the credit spent is given back
+// when the card is not charged, and the promo code uses too when the card
+// is declined, which is reported as payment.ErrPaymentDeclined. When the
+// outcome of the charge is unknown the credit stays spent, the card may
+// have been charged for the rest only; a payment settled later is reported
+// as payment.ErrPaymentPending and leaves the order waiting for the payment
+// webhook. Other errors may be retried, the caller giving up on the order
+// releases its promo code uses.
+func (h Handler) pay(spanCtx context.Context, o *order.Order, card payment.Card, useWallet bool) error {
+	log := logging.FromContext(spanCtx).With(zap.String("user_id", o.UserId), zap.String("order_id", o.Id))
+
+	var walletAmount float32
+	if useWallet {
+		var err error
+		walletAmount, err = h.walletStorage.Debit(spanCtx, o.UserId, o.Id, o.Total)
+		if err != nil {
+			log.Error("error on debiting wallet", zap.Error(err))
+			return err
+		}
+	}
+	if walletAmount > 0 {
+		// an order paid meanwhile keeps the credit it was debited
+		if err := h.orderStorage.SetWalletAmount(spanCtx, o.Id, walletAmount); err != nil {
+			log.Error("error on recording the store credit spent", zap.Error(err))
+			if !errors.Is(err, order.ErrNotPayable) {
+				h.reverseWallet(spanCtx, o.Id)
+			}
+			return err
+		}
+	}
+
+	// the card leg of the payment, left empty when store credit pays the
+	// whole order
+	var charge order.Payment
+	paymentRequest := payment.PaymentRequest{
+		OrderId:  o.Id,
+		Currency: "USD",
+		Card:     card,
+	}
+	if cardAmount := o.Total - walletAmount; cardAmount > 0 {
+		// exchange rate
+		exchangeResult, err := h.exchangeClient.GetTotal(spanCtx, o.Currency, "USD", cardAmount)
+		if err != nil {
+			log.Error("error exchange result", zap.Float32("total", cardAmount), zap.Error(err))
+			h.reverseWallet(spanCtx, o.Id)
+			return err
+		}
+
+		// the card leg is recorded first, what the payment service reports
+		// for the order is checked against it
+		paymentRequest.Amount = float64(exchangeResult.Total)
+		if err := h.orderStorage.SetCharge(spanCtx, o.Id, float32(paymentRequest.Amount), paymentRequest.Currency); err != nil {
+			log.Error("error on recording the card leg", zap.Error(err))
+			if !errors.Is(err, order.ErrNotPayable) {
+				h.reverseWallet(spanCtx, o.Id)
+			}
+			return err
+		}
+
+		// payment
+		paymentResult, err := h.paymentClient.MakePayment(spanCtx, paymentRequest)
+		if errors.Is(err, payment.ErrPaymentDeclined) {
+			log.Info("payment declined")
+			h.metrics.CheckoutDeclined()
+			h.reverseWallet(spanCtx, o.Id)
+			h.releasePromo(spanCtx, o.Id)
+			return err
+		}
+		if errors.Is(err, payment.ErrPaymentPending) {
+			log.Info("payment pending")
+			return err
+		}
+		if err != nil {
+			log.Error("error on payment result", zap.Error(err))
+			return err
+		}
+		charge.Id = paymentResult.Id
+		charge.Amount = float32(paymentRequest.Amount)
+		charge.Currency = paymentRequest.Currency
+	}
+
+	// update order with status complete
+	completed, err := h.complete(spanCtx, o.Id, charge)
+	if err != nil {
+		log.Error("error during completing the order", zap.String("payment_id", charge.Id), zap.Error(err))
+		return err
+	}
+	if completed {
+		h.metrics.CheckoutSucceeded(paymentRequest.Currency, paymentRequest.Amount)
+	}
+	return nil
+}
+
+// complete completes the order with charge, and reports whether this call
+// did. An order completed meanwhile with the same charge, by the webhook of
+// the payment or another attempt, is not an error.
+func (h Handler) complete(spanCtx context.Context, orderId string, charge order.Payment) (bool, error) {
+	err := h.orderStorage.Complete(spanCtx, orderId, charge)
+	if !errors.Is(err, order.ErrNotPayable) {
+		return err == nil, err
+	}
+
+	o, getErr := h.orderStorage.Get(spanCtx, orderId)
+	if getErr != nil {
+		return false, getErr
+	}
+	if o == nil || o.Status != order.StatusCompleted {
+		return false, err
+	}
+	samePayment := (o.PaymentId == nil && charge.Id == "") || (o.PaymentId != nil && *o.PaymentId == charge.Id)
+	if !samePayment {
+		return false, err
+	}
+	return false, nil
+}
+
+// enqueueCheckout hands the payment of the order to the checkout workers,
+// the buyer follows it at the Location.
+func (h Handler) enqueueCheckout(ctx echo.Context, spanCtx context.Context, o *order.Order, card payment.Card, useWallet bool) error {
+	// the job holds the token of the card, its details are not stored
+	token, err := h.paymentClient.TokenizeCard(spanCtx, card)
+	if errors.Is(err, payment.ErrPaymentDeclined) {
+		h.releasePromo(spanCtx, o.Id)
+		return ctx.NoContent(http.StatusPaymentRequired)
+	}
+	if err != nil {
+		h.log(ctx).Error("error on tokenizing card", zap.String("order_id", o.Id), zap.Error(err))
+		h.releasePromo(spanCtx, o.Id)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	sealed, err := h.sealer.Seal(o.Id, checkout.Card{Token: token})
+	if err != nil {
+		h.log(ctx).Error("error on sealing card", zap.String("order_id", o.Id), zap.Error(err))
+		h.releasePromo(spanCtx, o.Id)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	err = h.checkoutQueue.Enqueue(spanCtx, checkout.Job{OrderId: o.Id, UserId: o.UserId, Card: sealed, UseWallet: useWallet})
+	if errors.Is(err, checkout.ErrInProgress) {
+		// the order is given up unless it is the one being paid
+		if status, err := h.checkoutQueue.Status(spanCtx, o.Id); err != nil || status == nil || status.Finished() {
+			h.releasePromo(spanCtx, o.Id)
+		}
+		return ctx.NoContent(http.StatusConflict)
+	}
+	if err != nil {
+		h.log(ctx).Error("error on enqueuing checkout", zap.String("order_id", o.Id), zap.Error(err))
+		h.releasePromo(spanCtx, o.Id)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	h.log(ctx).Info("checkout enqueued", zap.String("order_id", o.Id))
+	ctx.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/api/v1/orders/%s/checkout", o.Id))
+	return ctx.JSON(http.StatusAccepted, toGenOrder(o))
+}
+
+// CheckoutProcessor pays the orders of the checkout jobs.
+func (h Handler) CheckoutProcessor() checkout.Processor {
+	return checkoutProcessor{h: h}
+}
+
+type checkoutProcessor struct {
+	h Handler
+}
+
+func (p checkoutProcessor) Process(ctx context.Context, job *checkout.Job) error {
+	span, spanCtx := telemetry.StartSpan(ctx, "ProcessCheckout", "job")
+	defer span.End()
+
+	o, err := p.h.orderStorage.Get(spanCtx, job.OrderId)
+	if err != nil {
+		return err
+	}
+	if o == nil {
+		return checkout.Fail("order not found")
+	}
+
+	switch o.Status {
+	case order.StatusCompleted:
+		// completed by an attempt that stopped before finishing the job
+		return nil
+	case order.StatusReady, order.StatusApproved:
+	default:
+		return checkout.Fail(fmt.Sprintf("order is %s", o.Status))
+	}
+
+	// an earlier attempt may have been charged without completing the order,
+	// charging again would charge twice
+	if job.Attempts > 1 {
+		completed, err := p.resume(spanCtx, o)
+		if err != nil || completed {
+			return err
+		}
+	}
+
+	// jobs enqueued before the cards were tokenized hold no token
+	card, err := p.h.sealer.Open(o.Id, job.Card)
+	if err != nil || card.Token == "" {
+		return checkout.Fail("card can't be read")
+	}
+
+	err = p.h.pay(spanCtx, o, payment.Card{Token: card.Token}, job.UseWallet)
+	if errors.Is(err, payment.ErrPaymentDeclined) {
+		return checkout.Fail("payment declined")
+	}
+	if errors.Is(err, payment.ErrPaymentPending) {
+		return checkout.ErrPending
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := p.h.cartStorage.Delete(spanCtx, o.UserId); err != nil {
+		logging.FromContext(spanCtx).Error("error on clearing user's cart", zap.String("user_id", o.UserId), zap.Error(err))
+	}
+	return nil
+}
+
+// resume completes the order with the payment captured by an earlier
+// attempt, if any. The payment must be the card leg recorded for the order
+// with the store credit spent on it still spent, otherwise it doesn't pay
+// the whole order and the job fails, reconciliation reports the charge.
+func (p checkoutProcessor) resume(spanCtx context.Context, o *order.Order) (bool, error) {
+	payments, err := p.h.paymentClient.FindPayments(spanCtx, o.Id)
+	if err != nil {
+		return false, err
+	}
+
+	for _, captured := range payments {
+		// charged by an earlier attempt, the webhook settles it
+		if captured.Status == payment.StatusPending {
+			return false, checkout.ErrPending
+		}
+		if captured.Status != payment.StatusCaptured {
+			continue
+		}
+
+		if !o.ChargedLeg(float32(captured.Amount), captured.Currency) {
+			return false, checkout.Fail("payment captured for another amount")
+		}
+		reversed, err := p.h.walletStorage.Reversed(spanCtx, o.Id)
+		if err != nil {
+			return false, err
+		}
+		if reversed > 0 {
+			return false, checkout.Fail("payment captured after the store credit was given back")
+		}
+
+		charge := order.Payment{Id: captured.Id, Amount: float32(captured.Amount), Currency: captured.Currency}
+		completed, err := p.h.complete(spanCtx, o.Id, charge)
+		if err != nil {
+			return false, err
+		}
+		if completed {
+			p.h.metrics.CheckoutSucceeded(captured.Currency, captured.Amount)
+		}
+		logging.FromContext(spanCtx).Info("checkout resumed with captured payment", zap.String("order_id", o.Id), zap.String("payment_id", captured.Id))
+
+		if err := p.h.cartStorage.Delete(spanCtx, o.UserId); err != nil {
+			logging.FromContext(spanCtx).Error("error on clearing user's cart", zap.String("user_id", o.UserId), zap.Error(err))
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p checkoutProcessor) Abandon(ctx context.Context, job *checkout.Job) {
+	span, spanCtx := telemetry.StartSpan(ctx, "AbandonCheckout", "job")
+	defer span.End()
+
+	p.h.reverseWallet(spanCtx, job.OrderId)
+	p.h.releasePromo(spanCtx, job.OrderId)
+}
+
+func (h Handler) GetCheckoutStatus(ctx echo.Context, uuid string, params gen.GetCheckoutStatusParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetCheckoutStatus", "request")
+	defer span.End()
+
+	if !isUUID(uuid) || h.checkoutQueue == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	status, err := h.checkoutQueue.Status(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting checkout status", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if status == nil || status.UserId != params.XUserId {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	o, err := h.orderStorage.Get(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting order by id", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if o == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	output := gen.CheckoutStatus{
+		OrderId:   status.OrderId,
+		State:     gen.CheckoutStatusState(status.State),
+		Attempts:  status.Attempts,
+		Order:     toGenOrder(o),
+		CreatedAt: status.CreatedAt,
+		UpdatedAt: status.UpdatedAt,
+	}
+	if status.Reason != "" {
+		reason := status.Reason
+		output.Reason = &reason
+	}
+
+	// polling clients stop once the checkout is finished
+	if !status.Finished() {
+		ctx.Response().Header().Set("Retry-After", "1")
+	}
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) StreamOrderEvents(ctx echo.Context, uuid string, params gen.StreamOrderEventsParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "StreamOrderEvents", "request")
+	defer span.End()
+
+	if !isUUID(uuid) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	// subscribing before reading the order, no transition is missed in
+	// between
+	stream, cancel := h.broker.Subscribe(uuid)
+	defer cancel()
+
+	o, err := h.orderStorage.Get(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting order by id", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if o == nil || o.UserId != params.XUserId {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.WriteHeader(http.StatusOK)
+
+	current := events.Event{OrderId: o.Id, Type: events.TypeStatus, State: o.Status, At: time.Now()}
+	if err := writeEvent(res, current); err != nil || final(current) {
+		return nil
+	}
+	if h.checkoutQueue != nil {
+		status, err := h.checkoutQueue.Status(spanCtx, uuid)
+		if err != nil {
+			h.log(ctx).Error("error on getting checkout status", zap.String("order_id", uuid), zap.Error(err))
+			return nil
+		}
+		if status != nil {
+			current := events.Event{OrderId: o.Id, Type: events.TypeCheckout, State: status.State, Reason: status.Reason, At: status.UpdatedAt}
+			if err := writeEvent(res, current); err != nil || final(current) {
+				return nil
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request().Context().Done():
+			return nil
+		case event, ok := <-stream:
+			// the broker is closed on shutdown
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(res, event); err != nil || final(event) {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := res.Write([]byte(": heartbeat\n\n")); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+func writeEvent(res *echo.Response, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// final tells whether the checkout of the order is over after the event.
+func final(event events.Event) bool {
+	if event.Type == events.TypeCheckout {
+		return event.State == checkout.StateSucceeded || event.State == checkout.StateFailed
+	}
+	switch event.State {
+	case order.StatusCompleted, order.StatusRejected, order.StatusFailed, order.StatusRefunded, order.StatusChargedBack:
+		return true
+	}
+	return false
+}
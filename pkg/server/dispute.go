@@ -0,0 +1,202 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"orderservice/pkg/repo/dispute"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (h Handler) ListOrderDisputes(ctx echo.Context, uuid string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ListOrderDisputes", "request")
+	defer span.End()
+
+	if !isUUID(uuid) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	o, err := h.orderStorage.Get(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting order by id", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if o == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	disputes, err := h.disputeStorage.ListByOrder(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on listing disputes", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := gen.DisputeList{Items: []gen.Dispute{}}
+	for i := range disputes {
+		output.Items = append(output.Items, toGenDispute(&disputes[i]))
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) OpenDispute(ctx echo.Context, uuid string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "OpenDispute", "request")
+	defer span.End()
+
+	request := new(gen.DisputeRequest)
+	if err := ctx.Bind(request); err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	// an amount means nothing without its currency
+	if (request.Amount == nil) != (request.Currency == nil) {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	if !isUUID(uuid) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	opening := dispute.Opening{OrderId: uuid, Reason: request.Reason}
+	if request.PaymentId != nil {
+		opening.PaymentId = *request.PaymentId
+	}
+	if request.Amount != nil {
+		opening.Amount, opening.Currency = *request.Amount, *request.Currency
+	}
+
+	d, err := h.disputeStorage.Open(spanCtx, opening)
+	if errors.Is(err, dispute.ErrOrderNotFound) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+	if errors.Is(err, dispute.ErrNotDisputable) || errors.Is(err, dispute.ErrAlreadyOpen) {
+		return ctx.NoContent(http.StatusConflict)
+	}
+	if err != nil {
+		h.log(ctx).Error("error on opening dispute", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	h.log(ctx).Info("dispute opened", zap.String("order_id", uuid), zap.String("dispute_id", d.Id), zap.String("reason", d.Reason))
+	return ctx.JSON(http.StatusCreated, toGenDispute(d))
+}
+
+func (h Handler) GetDispute(ctx echo.Context, id string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetDispute", "request")
+	defer span.End()
+
+	if !isUUID(id) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	d, err := h.disputeStorage.Get(spanCtx, id)
+	if err != nil {
+		h.log(ctx).Error("error on getting dispute", zap.String("dispute_id", id), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if d == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	return ctx.JSON(http.StatusOK, toGenDispute(d))
+}
+
+func (h Handler) SubmitDisputeEvidence(ctx echo.Context, id string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "SubmitDisputeEvidence", "request")
+	defer span.End()
+
+	request := new(gen.DisputeEvidenceRequest)
+	if err := ctx.Bind(request); err != nil || request.Evidence == "" {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	if !isUUID(id) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	d, err := h.disputeStorage.SubmitEvidence(spanCtx, id, request.Evidence)
+	if err != nil {
+		return h.disputeError(ctx, id, err)
+	}
+
+	h.log(ctx).Info("dispute evidence submitted", zap.String("dispute_id", id))
+	return ctx.JSON(http.StatusOK, toGenDispute(d))
+}
+
+func (h Handler) ResolveDispute(ctx echo.Context, id string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ResolveDispute", "request")
+	defer span.End()
+
+	request := new(gen.DisputeResolution)
+	if err := ctx.Bind(request); err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	if request.Outcome != gen.DisputeResolutionOutcomeWon && request.Outcome != gen.DisputeResolutionOutcomeLost {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	if !isUUID(id) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	d, err := h.disputeStorage.Resolve(spanCtx, id, string(request.Outcome))
+	if err != nil {
+		return h.disputeError(ctx, id, err)
+	}
+
+	h.log(ctx).Info("dispute resolved", zap.String("dispute_id", id), zap.String("order_id", d.OrderId), zap.String("state", d.State))
+	return ctx.JSON(http.StatusOK, toGenDispute(d))
+}
+
+func (h Handler) GetDisputeStats(ctx echo.Context, user string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetDisputeStats", "request")
+	defer span.End()
+
+	if !isUUID(user) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	stats, err := h.disputeStorage.Stats(spanCtx, user)
+	if err != nil {
+		h.log(ctx).Error("error on getting dispute stats", zap.String("user_id", user), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	return ctx.JSON(http.StatusOK, gen.DisputeStats{
+		UserId:       stats.UserId,
+		Total:        stats.Total,
+		Open:         stats.Open,
+		Won:          stats.Won,
+		Lost:         stats.Lost,
+		LastOpenedAt: stats.LastOpenedAt,
+	})
+}
+
+// disputeError answers the failure of a change of a dispute.
+func (h Handler) disputeError(ctx echo.Context, id string, err error) error {
+	switch {
+	case errors.Is(err, dispute.ErrNotFound):
+		return ctx.NoContent(http.StatusNotFound)
+	case errors.Is(err, dispute.ErrAlreadyResolved):
+		return ctx.NoContent(http.StatusConflict)
+	default:
+		h.log(ctx).Error("error on updating dispute", zap.String("dispute_id", id), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+}
+
+func toGenDispute(d *dispute.Dispute) gen.Dispute {
+	return gen.Dispute{
+		Id:                  d.Id,
+		OrderId:             d.OrderId,
+		UserId:              d.UserId,
+		PaymentId:           d.PaymentId,
+		State:               gen.DisputeState(d.State),
+		Reason:              d.Reason,
+		Amount:              d.Amount,
+		Currency:            d.Currency,
+		Evidence:            d.Evidence,
+		OpenedAt:            d.OpenedAt,
+		EvidenceSubmittedAt: d.EvidenceSubmittedAt,
+		ResolvedAt:          d.ResolvedAt,
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"orderservice/pkg/download"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (h Handler) GetDownloadLink(ctx echo.Context, uuid string, id string, params gen.GetDownloadLinkParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetDownloadLink", "request")
+	defer span.End()
+
+	if h.downloads == nil {
+		return ctx.NoContent(http.StatusServiceUnavailable)
+	}
+
+	if !isUUID(uuid) || !isUUID(id) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	// the entitlement of a redeemed gift points at the order of its buyer,
+	// access is checked on the entitlement rather than on the order; a
+	// refunded or disputed order leaves it inactive
+	e, err := h.entitlementStorage.Get(spanCtx, params.XUserId, id)
+	if err != nil {
+		h.log(ctx).Error("error on getting entitlement", zap.String("user_id", params.XUserId), zap.String("product_id", id), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if e == nil || e.OrderId != uuid {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+	if !e.Active() {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	p, err := h.productClient.GetByUUID(spanCtx, id)
+	if err != nil {
+		h.log(ctx).Error("error on getting product", zap.String("product_id", id), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if p.UploadId == "" || p.ObjectId == "" {
+		h.log(ctx).Error("product has no uploaded file", zap.String("product_id", id))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	link, err := h.downloads.Issue(spanCtx, params.XUserId, id, download.ObjectPath(p.UploadId, p.ObjectId), p.OriginalName)
+	if errors.Is(err, download.ErrLimitReached) {
+		return ctx.NoContent(http.StatusTooManyRequests)
+	}
+	if err != nil {
+		h.log(ctx).Error("error on issuing download link", zap.String("user_id", params.XUserId), zap.String("product_id", id), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	h.log(ctx).Info("download link issued", zap.String("order_id", uuid), zap.String("product_id", id), zap.Time("expires_at", link.ExpiresAt))
+	return ctx.JSON(http.StatusOK, gen.DownloadLink{
+		Url:                link.Url,
+		ExpiresAt:          link.ExpiresAt,
+		RemainingDownloads: link.Remaining,
+	})
+}
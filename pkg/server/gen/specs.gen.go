@@ -12,140 +12,1642 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
 )
 
+// Defines values for CheckoutStatusState.
+const (
+	CheckoutStatusStateFailed     CheckoutStatusState = "failed"
+	CheckoutStatusStatePending    CheckoutStatusState = "pending"
+	CheckoutStatusStateProcessing CheckoutStatusState = "processing"
+	CheckoutStatusStateQueued     CheckoutStatusState = "queued"
+	CheckoutStatusStateSucceeded  CheckoutStatusState = "succeeded"
+)
+
+// Defines values for DisputeState.
+const (
+	DisputeStateEvidenceSubmitted DisputeState = "evidence_submitted"
+	DisputeStateLost              DisputeState = "lost"
+	DisputeStateOpened            DisputeState = "opened"
+	DisputeStateWon               DisputeState = "won"
+)
+
+// Defines values for DisputeResolutionOutcome.
+const (
+	DisputeResolutionOutcomeLost DisputeResolutionOutcome = "lost"
+	DisputeResolutionOutcomeWon  DisputeResolutionOutcome = "won"
+)
+
+// Defines values for HealthCheckStatus.
+const (
+	HealthCheckStatusDown HealthCheckStatus = "down"
+	HealthCheckStatusUp   HealthCheckStatus = "up"
+)
+
+// Defines values for HealthReportStatus.
+const (
+	HealthReportStatusDown HealthReportStatus = "down"
+	HealthReportStatusUp   HealthReportStatus = "up"
+)
+
+// Defines values for LedgerEntryKind.
+const (
+	Capture    LedgerEntryKind = "capture"
+	Chargeback LedgerEntryKind = "chargeback"
+	Refund     LedgerEntryKind = "refund"
+)
+
+// Defines values for OrderGiftState.
+const (
+	OrderGiftStateActive   OrderGiftState = "active"
+	OrderGiftStateExpired  OrderGiftState = "expired"
+	OrderGiftStatePending  OrderGiftState = "pending"
+	OrderGiftStateRedeemed OrderGiftState = "redeemed"
+	OrderGiftStateRefunded OrderGiftState = "refunded"
+)
+
+// Defines values for PaymentWebhookResultOutcome.
+const (
+	ChargedBack PaymentWebhookResultOutcome = "charged_back"
+	Completed   PaymentWebhookResultOutcome = "completed"
+	DisputeWon  PaymentWebhookResultOutcome = "dispute_won"
+	Disputed    PaymentWebhookResultOutcome = "disputed"
+	Failed      PaymentWebhookResultOutcome = "failed"
+	Ignored     PaymentWebhookResultOutcome = "ignored"
+	Unchanged   PaymentWebhookResultOutcome = "unchanged"
+)
+
+// Defines values for WalletEntryKind.
+const (
+	Credit     WalletEntryKind = "credit"
+	Debit      WalletEntryKind = "debit"
+	Expiration WalletEntryKind = "expiration"
+	Reversal   WalletEntryKind = "reversal"
+)
+
+// Defines values for ListOrdersParamsSort.
+const (
+	Asc  ListOrdersParamsSort = "asc"
+	Desc ListOrdersParamsSort = "desc"
+)
+
+// Defines values for GetOrderInvoiceParamsFormat.
+const (
+	Html GetOrderInvoiceParamsFormat = "html"
+	Pdf  GetOrderInvoiceParamsFormat = "pdf"
+)
+
 // CardInfo defines model for CardInfo.
 type CardInfo struct {
-	Cvv     string `json:"cvv"`
-	ExpDate string `json:"exp_date"`
-	Number  string `json:"number"`
+	// BillingCountry ISO 3166-1 alpha-2 country of the billing address, used as tax evidence
+	BillingCountry *string `json:"billing_country,omitempty"`
+	Cvv            string  `json:"cvv"`
+	ExpDate        string  `json:"exp_date"`
+	Number         string  `json:"number"`
+}
+
+// CartItem defines model for CartItem.
+type CartItem struct {
+	Id    UUID    `json:"id"`
+	Name  string  `json:"name"`
+	Price float32 `json:"price"`
+}
+
+// CartTotals defines model for CartTotals.
+type CartTotals struct {
+	Discounts []DiscountLine `json:"discounts"`
+	PromoCode *string        `json:"promo_code,omitempty"`
+	Subtotal  float32        `json:"subtotal"`
+	Total     float32        `json:"total"`
+}
+
+// CheckoutRequest defines model for CheckoutRequest.
+type CheckoutRequest struct {
+	// BillingCountry ISO 3166-1 alpha-2 country of the billing address, used as tax evidence
+	BillingCountry *string `json:"billing_country,omitempty"`
+	Cvv            string  `json:"cvv"`
+	ExpDate        string  `json:"exp_date"`
+
+	// Gift recipient of a gift, by email or user id
+	Gift   *GiftRequest `json:"gift,omitempty"`
+	Number string       `json:"number"`
+
+	// UseWallet pay with the store credit first, the card pays the rest
+	UseWallet *bool `json:"use_wallet,omitempty"`
+}
+
+// CheckoutStatus defines model for CheckoutStatus.
+type CheckoutStatus struct {
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	Order     Order     `json:"order"`
+	OrderId   UUID      `json:"order_id"`
+
+	// Reason why the last attempt failed
+	Reason    *string             `json:"reason,omitempty"`
+	State     CheckoutStatusState `json:"state"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// CheckoutStatusState defines model for CheckoutStatus.State.
+type CheckoutStatusState string
+
+// DiscountLine defines model for DiscountLine.
+type DiscountLine struct {
+	Amount      float32 `json:"amount"`
+	Code        string  `json:"code"`
+	Description string  `json:"description"`
+}
+
+// Dispute defines model for Dispute.
+type Dispute struct {
+	// Amount amount disputed, in currency
+	Amount              float32      `json:"amount"`
+	Currency            string       `json:"currency"`
+	Evidence            string       `json:"evidence"`
+	EvidenceSubmittedAt *time.Time   `json:"evidence_submitted_at,omitempty"`
+	Id                  UUID         `json:"id"`
+	OpenedAt            time.Time    `json:"opened_at"`
+	OrderId             UUID         `json:"order_id"`
+	PaymentId           string       `json:"payment_id"`
+	Reason              string       `json:"reason"`
+	ResolvedAt          *time.Time   `json:"resolved_at,omitempty"`
+	State               DisputeState `json:"state"`
+	UserId              UUID         `json:"user_id"`
+}
+
+// DisputeState defines model for Dispute.State.
+type DisputeState string
+
+// DisputeEvidenceRequest defines model for DisputeEvidenceRequest.
+type DisputeEvidenceRequest struct {
+	Evidence string `json:"evidence"`
+}
+
+// DisputeList defines model for DisputeList.
+type DisputeList struct {
+	Items []Dispute `json:"items"`
+}
+
+// DisputeRequest defines model for DisputeRequest.
+type DisputeRequest struct {
+	// Amount amount disputed, the payment of the order by default
+	Amount   *float32 `json:"amount,omitempty"`
+	Currency *string  `json:"currency,omitempty"`
+
+	// PaymentId payment disputed, the payment of the order by default
+	PaymentId *string `json:"payment_id,omitempty"`
+	Reason    string  `json:"reason"`
+}
+
+// DisputeResolution defines model for DisputeResolution.
+type DisputeResolution struct {
+	Outcome DisputeResolutionOutcome `json:"outcome"`
+}
+
+// DisputeResolutionOutcome defines model for DisputeResolution.Outcome.
+type DisputeResolutionOutcome string
+
+// DisputeStats defines model for DisputeStats.
+type DisputeStats struct {
+	LastOpenedAt *time.Time `json:"last_opened_at,omitempty"`
+	Lost         int        `json:"lost"`
+	Open         int        `json:"open"`
+	Total        int        `json:"total"`
+	UserId       UUID       `json:"user_id"`
+	Won          int        `json:"won"`
+}
+
+// DownloadLink defines model for DownloadLink.
+type DownloadLink struct {
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// RemainingDownloads links the user can still get for the product in the current window
+	RemainingDownloads *int   `json:"remaining_downloads,omitempty"`
+	Url                string `json:"url"`
+}
+
+// Entitlement defines model for Entitlement.
+type Entitlement struct {
+	Name        string    `json:"name"`
+	OrderId     UUID      `json:"order_id"`
+	ProductId   UUID      `json:"product_id"`
+	PurchasedAt time.Time `json:"purchased_at"`
+	UserId      UUID      `json:"user_id"`
+}
+
+// GiftRedemption defines model for GiftRedemption.
+type GiftRedemption struct {
+	Items   []CartItem `json:"items"`
+	Message *string    `json:"message,omitempty"`
+	OrderId UUID       `json:"order_id"`
+}
+
+// GiftRequest recipient of a gift, by email or user id
+type GiftRequest struct {
+	Message         *string `json:"message,omitempty"`
+	RecipientEmail  *string `json:"recipient_email,omitempty"`
+	RecipientUserId *UUID   `json:"recipient_user_id,omitempty"`
+}
+
+// HealthCheck defines model for HealthCheck.
+type HealthCheck struct {
+	LatencyMs float32           `json:"latency_ms"`
+	Status    HealthCheckStatus `json:"status"`
+}
+
+// HealthCheckStatus defines model for HealthCheck.Status.
+type HealthCheckStatus string
+
+// HealthReport defines model for HealthReport.
+type HealthReport struct {
+	CheckedAt time.Time              `json:"checked_at"`
+	Checks    map[string]HealthCheck `json:"checks"`
+	Status    HealthReportStatus     `json:"status"`
+}
+
+// HealthReportStatus defines model for HealthReport.Status.
+type HealthReportStatus string
+
+// LedgerBalance defines model for LedgerBalance.
+type LedgerBalance struct {
+	Account string `json:"account"`
+
+	// Balance debit less credit in cents
+	Balance int64 `json:"balance"`
+
+	// Credit sum of the credits in cents
+	Credit   int64  `json:"credit"`
+	Currency string `json:"currency"`
+
+	// Debit sum of the debits in cents
+	Debit int64 `json:"debit"`
+}
+
+// LedgerCheck defines model for LedgerCheck.
+type LedgerCheck struct {
+	Balanced   bool              `json:"balanced"`
+	Imbalances []LedgerImbalance `json:"imbalances"`
+}
+
+// LedgerEntry defines model for LedgerEntry.
+type LedgerEntry struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Id        UUID            `json:"id"`
+	Kind      LedgerEntryKind `json:"kind"`
+	Postings  []LedgerPosting `json:"postings"`
+}
+
+// LedgerEntryKind defines model for LedgerEntry.Kind.
+type LedgerEntryKind string
+
+// LedgerImbalance defines model for LedgerImbalance.
+type LedgerImbalance struct {
+	// Amount sum of the postings in the currency in cents
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	EntryId  UUID   `json:"entry_id"`
+	OrderId  UUID   `json:"order_id"`
+}
+
+// LedgerPosting defines model for LedgerPosting.
+type LedgerPosting struct {
+	Account string `json:"account"`
+
+	// Amount in cents, debits positive and credits negative
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// Library defines model for Library.
+type Library struct {
+	Items      []LibraryItem `json:"items"`
+	NextCursor *string       `json:"next_cursor,omitempty"`
+}
+
+// LibraryItem defines model for LibraryItem.
+type LibraryItem struct {
+	Name        string    `json:"name"`
+	OrderId     UUID      `json:"order_id"`
+	ProductId   UUID      `json:"product_id"`
+	PurchasedAt time.Time `json:"purchased_at"`
+}
+
+// Order defines model for Order.
+type Order struct {
+	CreatedAt time.Time      `json:"created_at"`
+	Discounts []DiscountLine `json:"discounts"`
+	Gift      *OrderGift     `json:"gift,omitempty"`
+	Id        UUID           `json:"id"`
+	Items     []CartItem     `json:"items"`
+	PaymentId *UUID          `json:"payment_id,omitempty"`
+	Status    string         `json:"status"`
+	Subtotal  float32        `json:"subtotal"`
+
+	// TaxCountry country the order is taxed in
+	TaxCountry *string   `json:"tax_country,omitempty"`
+	Taxes      []TaxLine `json:"taxes"`
+	Total      float32   `json:"total"`
+
+	// WalletAmount part of the total paid with store credit, the card paid the rest
+	WalletAmount float32 `json:"wallet_amount"`
+}
+
+// OrderGift defines model for OrderGift.
+type OrderGift struct {
+	// Code code the recipient redeems the gift with, usable once the order is paid
+	Code            string         `json:"code"`
+	ExpiresAt       *time.Time     `json:"expires_at,omitempty"`
+	Message         *string        `json:"message,omitempty"`
+	RecipientEmail  *string        `json:"recipient_email,omitempty"`
+	RecipientUserId *UUID          `json:"recipient_user_id,omitempty"`
+	RedeemedAt      *time.Time     `json:"redeemed_at,omitempty"`
+	State           OrderGiftState `json:"state"`
+}
+
+// OrderGiftState defines model for OrderGift.State.
+type OrderGiftState string
+
+// OrderList defines model for OrderList.
+type OrderList struct {
+	Items      []Order `json:"items"`
+	NextCursor *string `json:"next_cursor,omitempty"`
+	PrevCursor *string `json:"prev_cursor,omitempty"`
+}
+
+// OwnedProductsError defines model for OwnedProductsError.
+type OwnedProductsError struct {
+	Message         string `json:"message"`
+	OwnedProductIds []UUID `json:"owned_product_ids"`
+}
+
+// PaymentWebhookEvent defines model for PaymentWebhookEvent.
+type PaymentWebhookEvent struct {
+	Amount    *float32  `json:"amount,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Currency  *string   `json:"currency,omitempty"`
+	Id        string    `json:"id"`
+	OrderId   string    `json:"order_id"`
+	PaymentId string    `json:"payment_id"`
+	Reason    *string   `json:"reason,omitempty"`
+
+	// Type payment.captured, payment.failed, payment.chargeback, payment.dispute_won or payment.dispute_lost, others are ignored
+	Type string `json:"type"`
+}
+
+// PaymentWebhookResult defines model for PaymentWebhookResult.
+type PaymentWebhookResult struct {
+	// Duplicate the event was processed by an earlier delivery
+	Duplicate bool                        `json:"duplicate"`
+	EventId   string                      `json:"event_id"`
+	Outcome   PaymentWebhookResultOutcome `json:"outcome"`
+}
+
+// PaymentWebhookResultOutcome defines model for PaymentWebhookResult.Outcome.
+type PaymentWebhookResultOutcome string
+
+// PromoCodeError defines model for PromoCodeError.
+type PromoCodeError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PromoCodeRequest defines model for PromoCodeRequest.
+type PromoCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// ReviewOrder defines model for ReviewOrder.
+type ReviewOrder struct {
+	CreatedAt    time.Time  `json:"created_at"`
+	Id           UUID       `json:"id"`
+	Items        []CartItem `json:"items"`
+	RiskDecision *string    `json:"risk_decision,omitempty"`
+	RiskReasons  []string   `json:"risk_reasons"`
+	Status       string     `json:"status"`
+	Total        float32    `json:"total"`
+	UserId       UUID       `json:"user_id"`
+}
+
+// TaxLine defines model for TaxLine.
+type TaxLine struct {
+	Amount  float32 `json:"amount"`
+	Country string  `json:"country"`
+
+	// Inclusive whether the tax is part of the item prices or added on top of them
+	Inclusive bool    `json:"inclusive"`
+	Net       float32 `json:"net"`
+
+	// Rate percentage
+	Rate float32 `json:"rate"`
+}
+
+// UUID defines model for UUID.
+type UUID = string
+
+// UnredeemedGift defines model for UnredeemedGift.
+type UnredeemedGift struct {
+	BuyerId UUID   `json:"buyer_id"`
+	Code    string `json:"code"`
+
+	// Expired past its expiry but not marked yet
+	Expired         bool       `json:"expired"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	OrderId         UUID       `json:"order_id"`
+	RecipientEmail  *string    `json:"recipient_email,omitempty"`
+	RecipientUserId *UUID      `json:"recipient_user_id,omitempty"`
+}
+
+// WalletBalance defines model for WalletBalance.
+type WalletBalance struct {
+	Balance  float32 `json:"balance"`
+	Currency string  `json:"currency"`
+
+	// Expiring parts of the balance expiring unless spent before, the ones expiring first first
+	Expiring []WalletExpiring `json:"expiring"`
+}
+
+// WalletCreditRequest defines model for WalletCreditRequest.
+type WalletCreditRequest struct {
+	Amount    float32    `json:"amount"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	OrderId   *UUID      `json:"order_id,omitempty"`
+	Reason    string     `json:"reason"`
+}
+
+// WalletEntry defines model for WalletEntry.
+type WalletEntry struct {
+	Amount    float32         `json:"amount"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Id        UUID            `json:"id"`
+	Kind      WalletEntryKind `json:"kind"`
+	OrderId   *UUID           `json:"order_id,omitempty"`
+	Reason    string          `json:"reason"`
+}
+
+// WalletEntryKind defines model for WalletEntry.Kind.
+type WalletEntryKind string
+
+// WalletExpiring defines model for WalletExpiring.
+type WalletExpiring struct {
+	Amount    float32   `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WalletHistory defines model for WalletHistory.
+type WalletHistory struct {
+	Entries    []WalletEntry `json:"entries"`
+	NextCursor *string       `json:"next_cursor,omitempty"`
+}
+
+// ListUnredeemedGiftsParams defines parameters for ListUnredeemedGifts.
+type ListUnredeemedGiftsParams struct {
+	// Limit maximum number of gifts
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetLedgerBalancesParams defines parameters for GetLedgerBalances.
+type GetLedgerBalancesParams struct {
+	// From only entries recorded at or after this time
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To only entries recorded before this time
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+}
+
+// CheckLedgerParams defines parameters for CheckLedger.
+type CheckLedgerParams struct {
+	// From only entries recorded at or after this time
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To only entries recorded before this time
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+}
+
+// RefundOrderParams defines parameters for RefundOrder.
+type RefundOrderParams struct {
+	// StoreCredit pay the total back as store credit
+	StoreCredit *bool `form:"store_credit,omitempty" json:"store_credit,omitempty"`
+}
+
+// ListReviewsParams defines parameters for ListReviews.
+type ListReviewsParams struct {
+	// Limit maximum number of orders
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ReceivePaymentWebhookParams defines parameters for ReceivePaymentWebhook.
+type ReceivePaymentWebhookParams struct {
+	// XPaymentSignature t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">
+	XPaymentSignature string `json:"X-Payment-Signature"`
+}
+
+// ClearCartParams defines parameters for ClearCart.
+type ClearCartParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// GetCartParams defines parameters for GetCart.
+type GetCartParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// UpdateCartJSONBody defines parameters for UpdateCart.
+type UpdateCartJSONBody = []UUID
+
+// UpdateCartParams defines parameters for UpdateCart.
+type UpdateCartParams struct {
+	// Gift the cart is bought as a gift, so products the user owns are allowed
+	Gift *bool `form:"gift,omitempty" json:"gift,omitempty"`
+
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// CheckoutCartParams defines parameters for CheckoutCart.
+type CheckoutCartParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// RemovePromoCodeParams defines parameters for RemovePromoCode.
+type RemovePromoCodeParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// ApplyPromoCodeParams defines parameters for ApplyPromoCode.
+type ApplyPromoCodeParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// RedeemGiftParams defines parameters for RedeemGift.
+type RedeemGiftParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// GetLibraryParams defines parameters for GetLibrary.
+type GetLibraryParams struct {
+	// Cursor opaque cursor taken from next_cursor of a previous page
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+
+	// Limit maximum number of products in a page
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// ListOrdersParams defines parameters for ListOrders.
+type ListOrdersParams struct {
+	// Cursor opaque cursor taken from next_cursor or prev_cursor of a previous page
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+
+	// Limit maximum number of orders in a page
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Status only orders with the given status
+	Status *string `form:"status,omitempty" json:"status,omitempty"`
+
+	// CreatedAfter only orders created at or after the given time
+	CreatedAfter *time.Time `form:"created_after,omitempty" json:"created_after,omitempty"`
+
+	// CreatedBefore only orders created before the given time
+	CreatedBefore *time.Time `form:"created_before,omitempty" json:"created_before,omitempty"`
+
+	// Sort sort direction on creation time
+	Sort *ListOrdersParamsSort `form:"sort,omitempty" json:"sort,omitempty"`
+
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// ListOrdersParamsSort defines parameters for ListOrders.
+type ListOrdersParamsSort string
+
+// GetOrderParams defines parameters for GetOrder.
+type GetOrderParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// GetCheckoutStatusParams defines parameters for GetCheckoutStatus.
+type GetCheckoutStatusParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// StreamOrderEventsParams defines parameters for StreamOrderEvents.
+type StreamOrderEventsParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// GetOrderInvoiceParams defines parameters for GetOrderInvoice.
+type GetOrderInvoiceParams struct {
+	// Format document format
+	Format *GetOrderInvoiceParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// GetOrderInvoiceParamsFormat defines parameters for GetOrderInvoice.
+type GetOrderInvoiceParamsFormat string
+
+// GetDownloadLinkParams defines parameters for GetDownloadLink.
+type GetDownloadLinkParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// GetWalletParams defines parameters for GetWallet.
+type GetWalletParams struct {
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
+}
+
+// ListWalletEntriesParams defines parameters for ListWalletEntries.
+type ListWalletEntriesParams struct {
+	// Cursor opaque cursor taken from next_cursor of a previous page
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+
+	// Limit maximum number of entries in a page
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// XUserId user uuid
+	XUserId UUID `json:"x-user-id"`
 }
 
-// CartItem defines model for CartItem.
-type CartItem struct {
-	Id    string  `json:"id"`
-	Name  string  `json:"name"`
-	Price float32 `json:"price"`
-}
+// SubmitDisputeEvidenceJSONRequestBody defines body for SubmitDisputeEvidence for application/json ContentType.
+type SubmitDisputeEvidenceJSONRequestBody = DisputeEvidenceRequest
+
+// ResolveDisputeJSONRequestBody defines body for ResolveDispute for application/json ContentType.
+type ResolveDisputeJSONRequestBody = DisputeResolution
+
+// OpenDisputeJSONRequestBody defines body for OpenDispute for application/json ContentType.
+type OpenDisputeJSONRequestBody = DisputeRequest
+
+// CreditWalletJSONRequestBody defines body for CreditWallet for application/json ContentType.
+type CreditWalletJSONRequestBody = WalletCreditRequest
+
+// ReceivePaymentWebhookJSONRequestBody defines body for ReceivePaymentWebhook for application/json ContentType.
+type ReceivePaymentWebhookJSONRequestBody = PaymentWebhookEvent
+
+// UpdateCartJSONRequestBody defines body for UpdateCart for application/json ContentType.
+type UpdateCartJSONRequestBody = UpdateCartJSONBody
+
+// CheckoutCartJSONRequestBody defines body for CheckoutCart for application/json ContentType.
+type CheckoutCartJSONRequestBody = CheckoutRequest
+
+// ApplyPromoCodeJSONRequestBody defines body for ApplyPromoCode for application/json ContentType.
+type ApplyPromoCodeJSONRequestBody = PromoCodeRequest
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+
+	// (GET /_health)
+	Health(ctx echo.Context) error
+
+	// (GET /_health/live)
+	HealthLive(ctx echo.Context) error
+
+	// (GET /_health/ready)
+	HealthReady(ctx echo.Context) error
+
+	// (GET /_private/api/v1/disputes/{id})
+	GetDispute(ctx echo.Context, id UUID) error
+
+	// (POST /_private/api/v1/disputes/{id}/evidence)
+	SubmitDisputeEvidence(ctx echo.Context, id UUID) error
+
+	// (POST /_private/api/v1/disputes/{id}/resolve)
+	ResolveDispute(ctx echo.Context, id UUID) error
+
+	// (GET /_private/api/v1/entitlements/{user}/{product})
+	GetEntitlement(ctx echo.Context, user UUID, product UUID) error
+
+	// (GET /_private/api/v1/gifts/unredeemed)
+	ListUnredeemedGifts(ctx echo.Context, params ListUnredeemedGiftsParams) error
+
+	// (GET /_private/api/v1/ledger/accounts/{account}/balances)
+	GetLedgerBalances(ctx echo.Context, account string, params GetLedgerBalancesParams) error
+
+	// (GET /_private/api/v1/ledger/check)
+	CheckLedger(ctx echo.Context, params CheckLedgerParams) error
+
+	// (GET /_private/api/v1/orders/{uuid})
+	GetOrderDetail(ctx echo.Context, uuid UUID) error
+
+	// (GET /_private/api/v1/orders/{uuid}/disputes)
+	ListOrderDisputes(ctx echo.Context, uuid UUID) error
+
+	// (POST /_private/api/v1/orders/{uuid}/disputes)
+	OpenDispute(ctx echo.Context, uuid UUID) error
+
+	// (GET /_private/api/v1/orders/{uuid}/journal)
+	GetOrderJournal(ctx echo.Context, uuid UUID) error
+
+	// (POST /_private/api/v1/orders/{uuid}/refund)
+	RefundOrder(ctx echo.Context, uuid UUID, params RefundOrderParams) error
+
+	// (GET /_private/api/v1/reviews)
+	ListReviews(ctx echo.Context, params ListReviewsParams) error
+
+	// (POST /_private/api/v1/reviews/{uuid}/approve)
+	ApproveReview(ctx echo.Context, uuid UUID) error
+
+	// (POST /_private/api/v1/reviews/{uuid}/reject)
+	RejectReview(ctx echo.Context, uuid UUID) error
+
+	// (GET /_private/api/v1/users/{user}/disputes/stats)
+	GetDisputeStats(ctx echo.Context, user UUID) error
+
+	// (POST /_private/api/v1/wallets/{user}/credits)
+	CreditWallet(ctx echo.Context, user UUID) error
+
+	// (POST /_private/api/v1/webhooks/payment)
+	ReceivePaymentWebhook(ctx echo.Context, params ReceivePaymentWebhookParams) error
+
+	// (DELETE /api/v1/cart)
+	ClearCart(ctx echo.Context, params ClearCartParams) error
+
+	// (GET /api/v1/cart)
+	GetCart(ctx echo.Context, params GetCartParams) error
+
+	// (POST /api/v1/cart)
+	UpdateCart(ctx echo.Context, params UpdateCartParams) error
+
+	// (POST /api/v1/cart/checkout)
+	CheckoutCart(ctx echo.Context, params CheckoutCartParams) error
+
+	// (DELETE /api/v1/cart/promo)
+	RemovePromoCode(ctx echo.Context, params RemovePromoCodeParams) error
+
+	// (PUT /api/v1/cart/promo)
+	ApplyPromoCode(ctx echo.Context, params ApplyPromoCodeParams) error
+
+	// (POST /api/v1/gifts/{code}/redeem)
+	RedeemGift(ctx echo.Context, code string, params RedeemGiftParams) error
+
+	// (GET /api/v1/library)
+	GetLibrary(ctx echo.Context, params GetLibraryParams) error
+
+	// (GET /api/v1/orders)
+	ListOrders(ctx echo.Context, params ListOrdersParams) error
+
+	// (GET /api/v1/orders/{uuid})
+	GetOrder(ctx echo.Context, uuid UUID, params GetOrderParams) error
+
+	// (GET /api/v1/orders/{uuid}/checkout)
+	GetCheckoutStatus(ctx echo.Context, uuid UUID, params GetCheckoutStatusParams) error
+
+	// (GET /api/v1/orders/{uuid}/events)
+	StreamOrderEvents(ctx echo.Context, uuid UUID, params StreamOrderEventsParams) error
+
+	// (GET /api/v1/orders/{uuid}/invoice)
+	GetOrderInvoice(ctx echo.Context, uuid UUID, params GetOrderInvoiceParams) error
+
+	// (GET /api/v1/orders/{uuid}/items/{id}/download)
+	GetDownloadLink(ctx echo.Context, uuid UUID, id UUID, params GetDownloadLinkParams) error
+
+	// (GET /api/v1/wallet)
+	GetWallet(ctx echo.Context, params GetWalletParams) error
+
+	// (GET /api/v1/wallet/entries)
+	ListWalletEntries(ctx echo.Context, params ListWalletEntriesParams) error
+}
+
+// ServerInterfaceWrapper converts echo contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler ServerInterface
+}
+
+// Health converts echo context to params.
+func (w *ServerInterfaceWrapper) Health(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Health(ctx)
+	return err
+}
+
+// HealthLive converts echo context to params.
+func (w *ServerInterfaceWrapper) HealthLive(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.HealthLive(ctx)
+	return err
+}
+
+// HealthReady converts echo context to params.
+func (w *ServerInterfaceWrapper) HealthReady(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.HealthReady(ctx)
+	return err
+}
+
+// GetDispute converts echo context to params.
+func (w *ServerInterfaceWrapper) GetDispute(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetDispute(ctx, id)
+	return err
+}
+
+// SubmitDisputeEvidence converts echo context to params.
+func (w *ServerInterfaceWrapper) SubmitDisputeEvidence(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.SubmitDisputeEvidence(ctx, id)
+	return err
+}
+
+// ResolveDispute converts echo context to params.
+func (w *ServerInterfaceWrapper) ResolveDispute(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ResolveDispute(ctx, id)
+	return err
+}
+
+// GetEntitlement converts echo context to params.
+func (w *ServerInterfaceWrapper) GetEntitlement(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "user" -------------
+	var user UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "user", runtime.ParamLocationPath, ctx.Param("user"), &user)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter user: %s", err))
+	}
+
+	// ------------- Path parameter "product" -------------
+	var product UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "product", runtime.ParamLocationPath, ctx.Param("product"), &product)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter product: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetEntitlement(ctx, user, product)
+	return err
+}
+
+// ListUnredeemedGifts converts echo context to params.
+func (w *ServerInterfaceWrapper) ListUnredeemedGifts(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListUnredeemedGiftsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListUnredeemedGifts(ctx, params)
+	return err
+}
+
+// GetLedgerBalances converts echo context to params.
+func (w *ServerInterfaceWrapper) GetLedgerBalances(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "account" -------------
+	var account string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "account", runtime.ParamLocationPath, ctx.Param("account"), &account)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter account: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetLedgerBalancesParams
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", ctx.QueryParams(), &params.From)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter from: %s", err))
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", ctx.QueryParams(), &params.To)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter to: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetLedgerBalances(ctx, account, params)
+	return err
+}
+
+// CheckLedger converts echo context to params.
+func (w *ServerInterfaceWrapper) CheckLedger(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CheckLedgerParams
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", ctx.QueryParams(), &params.From)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter from: %s", err))
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", ctx.QueryParams(), &params.To)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter to: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CheckLedger(ctx, params)
+	return err
+}
+
+// GetOrderDetail converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOrderDetail(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetOrderDetail(ctx, uuid)
+	return err
+}
+
+// ListOrderDisputes converts echo context to params.
+func (w *ServerInterfaceWrapper) ListOrderDisputes(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListOrderDisputes(ctx, uuid)
+	return err
+}
+
+// OpenDispute converts echo context to params.
+func (w *ServerInterfaceWrapper) OpenDispute(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.OpenDispute(ctx, uuid)
+	return err
+}
+
+// GetOrderJournal converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOrderJournal(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetOrderJournal(ctx, uuid)
+	return err
+}
+
+// RefundOrder converts echo context to params.
+func (w *ServerInterfaceWrapper) RefundOrder(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params RefundOrderParams
+	// ------------- Optional query parameter "store_credit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "store_credit", ctx.QueryParams(), &params.StoreCredit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter store_credit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RefundOrder(ctx, uuid, params)
+	return err
+}
+
+// ListReviews converts echo context to params.
+func (w *ServerInterfaceWrapper) ListReviews(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListReviewsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListReviews(ctx, params)
+	return err
+}
+
+// ApproveReview converts echo context to params.
+func (w *ServerInterfaceWrapper) ApproveReview(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ApproveReview(ctx, uuid)
+	return err
+}
+
+// RejectReview converts echo context to params.
+func (w *ServerInterfaceWrapper) RejectReview(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RejectReview(ctx, uuid)
+	return err
+}
+
+// GetDisputeStats converts echo context to params.
+func (w *ServerInterfaceWrapper) GetDisputeStats(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "user" -------------
+	var user UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "user", runtime.ParamLocationPath, ctx.Param("user"), &user)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter user: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetDisputeStats(ctx, user)
+	return err
+}
+
+// CreditWallet converts echo context to params.
+func (w *ServerInterfaceWrapper) CreditWallet(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "user" -------------
+	var user UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "user", runtime.ParamLocationPath, ctx.Param("user"), &user)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter user: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreditWallet(ctx, user)
+	return err
+}
+
+// ReceivePaymentWebhook converts echo context to params.
+func (w *ServerInterfaceWrapper) ReceivePaymentWebhook(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ReceivePaymentWebhookParams
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "X-Payment-Signature" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Payment-Signature")]; found {
+		var XPaymentSignature string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Payment-Signature, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Payment-Signature", runtime.ParamLocationHeader, valueList[0], &XPaymentSignature)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Payment-Signature: %s", err))
+		}
+
+		params.XPaymentSignature = XPaymentSignature
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter X-Payment-Signature is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ReceivePaymentWebhook(ctx, params)
+	return err
+}
+
+// ClearCart converts echo context to params.
+func (w *ServerInterfaceWrapper) ClearCart(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ClearCartParams
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ClearCart(ctx, params)
+	return err
+}
+
+// GetCart converts echo context to params.
+func (w *ServerInterfaceWrapper) GetCart(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetCartParams
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetCart(ctx, params)
+	return err
+}
+
+// UpdateCart converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateCart(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateCartParams
+	// ------------- Optional query parameter "gift" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "gift", ctx.QueryParams(), &params.Gift)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter gift: %s", err))
+	}
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateCart(ctx, params)
+	return err
+}
+
+// CheckoutCart converts echo context to params.
+func (w *ServerInterfaceWrapper) CheckoutCart(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CheckoutCartParams
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CheckoutCart(ctx, params)
+	return err
+}
+
+// RemovePromoCode converts echo context to params.
+func (w *ServerInterfaceWrapper) RemovePromoCode(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params RemovePromoCodeParams
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RemovePromoCode(ctx, params)
+	return err
+}
+
+// ApplyPromoCode converts echo context to params.
+func (w *ServerInterfaceWrapper) ApplyPromoCode(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApplyPromoCodeParams
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
 
-// Order defines model for Order.
-type Order struct {
-	Id        string     `json:"id"`
-	Items     []CartItem `json:"items"`
-	PaymentId *string    `json:"payment_id,omitempty"`
-	Status    string     `json:"status"`
-	Total     float32    `json:"total"`
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ApplyPromoCode(ctx, params)
+	return err
 }
 
-// ClearCartParams defines parameters for ClearCart.
-type ClearCartParams struct {
-	// XUserId user uuid
-	XUserId string `json:"x-user-id"`
-}
+// RedeemGift converts echo context to params.
+func (w *ServerInterfaceWrapper) RedeemGift(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "code" -------------
+	var code string
 
-// GetCartParams defines parameters for GetCart.
-type GetCartParams struct {
-	// XUserId user uuid
-	XUserId string `json:"x-user-id"`
-}
+	err = runtime.BindStyledParameterWithLocation("simple", false, "code", runtime.ParamLocationPath, ctx.Param("code"), &code)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter code: %s", err))
+	}
 
-// UpdateCartJSONBody defines parameters for UpdateCart.
-type UpdateCartJSONBody = []string
+	// Parameter object where we will unmarshal all parameters from the context
+	var params RedeemGiftParams
 
-// UpdateCartParams defines parameters for UpdateCart.
-type UpdateCartParams struct {
-	// XUserId user uuid
-	XUserId string `json:"x-user-id"`
-}
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
 
-// CheckoutCartParams defines parameters for CheckoutCart.
-type CheckoutCartParams struct {
-	// XUserId user uuid
-	XUserId string `json:"x-user-id"`
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RedeemGift(ctx, code, params)
+	return err
 }
 
-// ListOrdersParams defines parameters for ListOrders.
-type ListOrdersParams struct {
-	// XUserId user uuid
-	XUserId string `json:"x-user-id"`
+// GetLibrary converts echo context to params.
+func (w *ServerInterfaceWrapper) GetLibrary(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetLibraryParams
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetLibrary(ctx, params)
+	return err
 }
 
-// UpdateCartJSONRequestBody defines body for UpdateCart for application/json ContentType.
-type UpdateCartJSONRequestBody = UpdateCartJSONBody
+// ListOrders converts echo context to params.
+func (w *ServerInterfaceWrapper) ListOrders(ctx echo.Context) error {
+	var err error
 
-// CheckoutCartJSONRequestBody defines body for CheckoutCart for application/json ContentType.
-type CheckoutCartJSONRequestBody = CardInfo
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListOrdersParams
+	// ------------- Optional query parameter "cursor" -------------
 
-// ServerInterface represents all server handlers.
-type ServerInterface interface {
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
 
-	// (GET /_health)
-	Health(ctx echo.Context) error
+	// ------------- Optional query parameter "limit" -------------
 
-	// (GET /_private/api/v1/orders/{uuid})
-	GetOrderDetail(ctx echo.Context, uuid string) error
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
 
-	// (DELETE /api/v1/cart)
-	ClearCart(ctx echo.Context, params ClearCartParams) error
+	// ------------- Optional query parameter "status" -------------
 
-	// (GET /api/v1/cart)
-	GetCart(ctx echo.Context, params GetCartParams) error
+	err = runtime.BindQueryParameter("form", true, false, "status", ctx.QueryParams(), &params.Status)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter status: %s", err))
+	}
 
-	// (POST /api/v1/cart)
-	UpdateCart(ctx echo.Context, params UpdateCartParams) error
+	// ------------- Optional query parameter "created_after" -------------
 
-	// (POST /api/v1/cart/checkout)
-	CheckoutCart(ctx echo.Context, params CheckoutCartParams) error
+	err = runtime.BindQueryParameter("form", true, false, "created_after", ctx.QueryParams(), &params.CreatedAfter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter created_after: %s", err))
+	}
 
-	// (GET /api/v1/orders)
-	ListOrders(ctx echo.Context, params ListOrdersParams) error
-}
+	// ------------- Optional query parameter "created_before" -------------
 
-// ServerInterfaceWrapper converts echo contexts to parameters.
-type ServerInterfaceWrapper struct {
-	Handler ServerInterface
+	err = runtime.BindQueryParameter("form", true, false, "created_before", ctx.QueryParams(), &params.CreatedBefore)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter created_before: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListOrders(ctx, params)
+	return err
 }
 
-// Health converts echo context to params.
-func (w *ServerInterfaceWrapper) Health(ctx echo.Context) error {
+// GetOrder converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOrder(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetOrderParams
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.Health(ctx)
+	err = w.Handler.GetOrder(ctx, uuid, params)
 	return err
 }
 
-// GetOrderDetail converts echo context to params.
-func (w *ServerInterfaceWrapper) GetOrderDetail(ctx echo.Context) error {
+// GetCheckoutStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) GetCheckoutStatus(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "uuid" -------------
-	var uuid string
+	var uuid UUID
 
 	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetCheckoutStatusParams
+
+	headers := ctx.Request().Header
+	// ------------- Required header parameter "x-user-id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
+		var XUserId UUID
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "x-user-id", runtime.ParamLocationHeader, valueList[0], &XUserId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter x-user-id: %s", err))
+		}
+
+		params.XUserId = XUserId
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Header parameter x-user-id is required, but not found"))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetOrderDetail(ctx, uuid)
+	err = w.Handler.GetCheckoutStatus(ctx, uuid, params)
 	return err
 }
 
-// ClearCart converts echo context to params.
-func (w *ServerInterfaceWrapper) ClearCart(ctx echo.Context) error {
+// StreamOrderEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) StreamOrderEvents(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params ClearCartParams
+	var params StreamOrderEventsParams
 
 	headers := ctx.Request().Header
 	// ------------- Required header parameter "x-user-id" -------------
 	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
-		var XUserId string
+		var XUserId UUID
 		n := len(valueList)
 		if n != 1 {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
@@ -162,21 +1664,34 @@ func (w *ServerInterfaceWrapper) ClearCart(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.ClearCart(ctx, params)
+	err = w.Handler.StreamOrderEvents(ctx, uuid, params)
 	return err
 }
 
-// GetCart converts echo context to params.
-func (w *ServerInterfaceWrapper) GetCart(ctx echo.Context) error {
+// GetOrderInvoice converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOrderInvoice(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params GetCartParams
+	var params GetOrderInvoiceParams
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
+	}
 
 	headers := ctx.Request().Header
 	// ------------- Required header parameter "x-user-id" -------------
 	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
-		var XUserId string
+		var XUserId UUID
 		n := len(valueList)
 		if n != 1 {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
@@ -193,21 +1708,36 @@ func (w *ServerInterfaceWrapper) GetCart(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetCart(ctx, params)
+	err = w.Handler.GetOrderInvoice(ctx, uuid, params)
 	return err
 }
 
-// UpdateCart converts echo context to params.
-func (w *ServerInterfaceWrapper) UpdateCart(ctx echo.Context) error {
+// GetDownloadLink converts echo context to params.
+func (w *ServerInterfaceWrapper) GetDownloadLink(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "uuid", runtime.ParamLocationPath, ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// ------------- Path parameter "id" -------------
+	var id UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params UpdateCartParams
+	var params GetDownloadLinkParams
 
 	headers := ctx.Request().Header
 	// ------------- Required header parameter "x-user-id" -------------
 	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
-		var XUserId string
+		var XUserId UUID
 		n := len(valueList)
 		if n != 1 {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
@@ -224,21 +1754,21 @@ func (w *ServerInterfaceWrapper) UpdateCart(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.UpdateCart(ctx, params)
+	err = w.Handler.GetDownloadLink(ctx, uuid, id, params)
 	return err
 }
 
-// CheckoutCart converts echo context to params.
-func (w *ServerInterfaceWrapper) CheckoutCart(ctx echo.Context) error {
+// GetWallet converts echo context to params.
+func (w *ServerInterfaceWrapper) GetWallet(ctx echo.Context) error {
 	var err error
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params CheckoutCartParams
+	var params GetWalletParams
 
 	headers := ctx.Request().Header
 	// ------------- Required header parameter "x-user-id" -------------
 	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
-		var XUserId string
+		var XUserId UUID
 		n := len(valueList)
 		if n != 1 {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
@@ -255,21 +1785,34 @@ func (w *ServerInterfaceWrapper) CheckoutCart(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.CheckoutCart(ctx, params)
+	err = w.Handler.GetWallet(ctx, params)
 	return err
 }
 
-// ListOrders converts echo context to params.
-func (w *ServerInterfaceWrapper) ListOrders(ctx echo.Context) error {
+// ListWalletEntries converts echo context to params.
+func (w *ServerInterfaceWrapper) ListWalletEntries(ctx echo.Context) error {
 	var err error
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params ListOrdersParams
+	var params ListWalletEntriesParams
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
 
 	headers := ctx.Request().Header
 	// ------------- Required header parameter "x-user-id" -------------
 	if valueList, found := headers[http.CanonicalHeaderKey("x-user-id")]; found {
-		var XUserId string
+		var XUserId UUID
 		n := len(valueList)
 		if n != 1 {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for x-user-id, got %d", n))
@@ -286,7 +1829,7 @@ func (w *ServerInterfaceWrapper) ListOrders(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.ListOrders(ctx, params)
+	err = w.Handler.ListWalletEntries(ctx, params)
 	return err
 }
 
@@ -319,33 +1862,144 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 	}
 
 	router.GET(baseURL+"/_health", wrapper.Health)
+	router.GET(baseURL+"/_health/live", wrapper.HealthLive)
+	router.GET(baseURL+"/_health/ready", wrapper.HealthReady)
+	router.GET(baseURL+"/_private/api/v1/disputes/:id", wrapper.GetDispute)
+	router.POST(baseURL+"/_private/api/v1/disputes/:id/evidence", wrapper.SubmitDisputeEvidence)
+	router.POST(baseURL+"/_private/api/v1/disputes/:id/resolve", wrapper.ResolveDispute)
+	router.GET(baseURL+"/_private/api/v1/entitlements/:user/:product", wrapper.GetEntitlement)
+	router.GET(baseURL+"/_private/api/v1/gifts/unredeemed", wrapper.ListUnredeemedGifts)
+	router.GET(baseURL+"/_private/api/v1/ledger/accounts/:account/balances", wrapper.GetLedgerBalances)
+	router.GET(baseURL+"/_private/api/v1/ledger/check", wrapper.CheckLedger)
 	router.GET(baseURL+"/_private/api/v1/orders/:uuid", wrapper.GetOrderDetail)
+	router.GET(baseURL+"/_private/api/v1/orders/:uuid/disputes", wrapper.ListOrderDisputes)
+	router.POST(baseURL+"/_private/api/v1/orders/:uuid/disputes", wrapper.OpenDispute)
+	router.GET(baseURL+"/_private/api/v1/orders/:uuid/journal", wrapper.GetOrderJournal)
+	router.POST(baseURL+"/_private/api/v1/orders/:uuid/refund", wrapper.RefundOrder)
+	router.GET(baseURL+"/_private/api/v1/reviews", wrapper.ListReviews)
+	router.POST(baseURL+"/_private/api/v1/reviews/:uuid/approve", wrapper.ApproveReview)
+	router.POST(baseURL+"/_private/api/v1/reviews/:uuid/reject", wrapper.RejectReview)
+	router.GET(baseURL+"/_private/api/v1/users/:user/disputes/stats", wrapper.GetDisputeStats)
+	router.POST(baseURL+"/_private/api/v1/wallets/:user/credits", wrapper.CreditWallet)
+	router.POST(baseURL+"/_private/api/v1/webhooks/payment", wrapper.ReceivePaymentWebhook)
 	router.DELETE(baseURL+"/api/v1/cart", wrapper.ClearCart)
 	router.GET(baseURL+"/api/v1/cart", wrapper.GetCart)
 	router.POST(baseURL+"/api/v1/cart", wrapper.UpdateCart)
 	router.POST(baseURL+"/api/v1/cart/checkout", wrapper.CheckoutCart)
+	router.DELETE(baseURL+"/api/v1/cart/promo", wrapper.RemovePromoCode)
+	router.PUT(baseURL+"/api/v1/cart/promo", wrapper.ApplyPromoCode)
+	router.POST(baseURL+"/api/v1/gifts/:code/redeem", wrapper.RedeemGift)
+	router.GET(baseURL+"/api/v1/library", wrapper.GetLibrary)
 	router.GET(baseURL+"/api/v1/orders", wrapper.ListOrders)
+	router.GET(baseURL+"/api/v1/orders/:uuid", wrapper.GetOrder)
+	router.GET(baseURL+"/api/v1/orders/:uuid/checkout", wrapper.GetCheckoutStatus)
+	router.GET(baseURL+"/api/v1/orders/:uuid/events", wrapper.StreamOrderEvents)
+	router.GET(baseURL+"/api/v1/orders/:uuid/invoice", wrapper.GetOrderInvoice)
+	router.GET(baseURL+"/api/v1/orders/:uuid/items/:id/download", wrapper.GetDownloadLink)
+	router.GET(baseURL+"/api/v1/wallet", wrapper.GetWallet)
+	router.GET(baseURL+"/api/v1/wallet/entries", wrapper.ListWalletEntries)
 
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/9yX328jNRDH/xXLIPGyl03p8bJPQJGgEhInIZ5QVE3tSePrru2zZ8NF1f7vaGxvmyZO",
-	"c1CJH/cUa+2xv/OZH3YepHKDdxYtRdk9yKg2OEAaXkHQ13bteOyD8xjIYJpR2y3/0M6j7GSkYOydnBqJ",
-	"H/2NBsLqpB2HWwyVqamRAT+MJqCW3e/zur3dmnTgqpkN3e17VMR7XkGga8LhWKLRdREw1NX5YFSaWbsw",
-	"AMlOrnsHJB8PLbIO1Roty67zHjWdvwSdXf8kkYZwyPPz4MuAa9nJL9qnYLUlUu0jg+nxYAgBdskr2A1o",
-	"6ebEQZGAxlidIkfQ78285H7ZZjaaHTgGwdamZJRylkARD3EA08tO3kIw8VuN20XvFPQsQ2NUwXgyzspO",
-	"/oS9j2KMGAQ5oQICoQChIFAjHDMWYLXwsGMxhno+PLEXv2LYGoXiu3fXspFbDDFvuVxcLJZ8kvNowRvZ",
-	"ycvFcnHJ4QTaJDjtzQahpw2P7zBJ5igCq7rWSVeaZjTROxtzdL9eLvnnuQux6DBR/OHCvbF3IudEnwL2",
-	"zfLyRRvrqGLH8YK7yAEpSlf8rb3xwWyBsAVv2u1FmxDF9mEcjZ5OevMjUmL2AxLHhUEEGJAw8AmH4jL2",
-	"e9yJlAuGvzG5uSw6yafJ/bShMGJTOk2tH6zqIDlj0CbB4H1vVJLcvo+s42Fvv5eqJVdiysSaHzr7vE+0",
-	"MCxIC0lOuRyoHnO/e87wqkcIXJnn8KVkLoQSuw2CTu2v0Pv4hpe8eTXCt5W8GpXCGNdj3++EYsWon7me",
-	"vFxNzck8+U95+NeS5JW99TiB2ImvYmpGdYjexQrF3zzfcf82yA8jRvre6d3fY3h8fZyBxZbC6Cgsoo7c",
-	"zW9R+B4UamFshnjoxHQU8YszOT0mtpoFva0VQFLBLXXtRltN/YOSb9UG1b0bE5t6PK/Kiv9nRM8UQ34P",
-	"VuKpIHDg1k6sXRDl2fEJEfwHGvuzjPBgzsU5X5Inb8efTczXY/ycG1+heb6QexOJHyOF2qlKqxdZMmL6",
-	"T98ODed3W37XGIsxPgEsj52pObTi4hNotXeG/9c8GuTmfLQ8PxEr613BcGjwLr8Jaibzc2FaTX8GAAD/",
-	"/4CpJCJcDQAA",
+	"H4sIAAAAAAAC/+w9aY/cNpZ/hdAOkA+rdh92AqSDBZJJjMQLD2zYE8xiY0+DJb6qYloiZZKq7oLR/33B",
+	"S6IkUir1lU7WX+wuiee7+C4+fc4KXtWcAVMyO/+cyWILFTZ//ogFecXWXP9dC16DUBTMmxUtS8o2FwVv",
+	"mBJ7/YiALAStFeUsO89evX+Dnp9+883RKcJlvcVHZ8i1RXyN1BaQGwJhQgRImaNGAkFYIoWvEewoAVZA",
+	"lmc1VgqEHvPfv/1w9L8fP5/d/C3LM7WvITvPpBKUbbKbPCt2O7POoPnJ0bcfPz/PX8Q7wHV9QbAC3aui",
+	"7DWwjdpm56eRpqypViCiw5+e5affxia4yTMBnxoqgGTnv/khgmntkj+2HfnqdyiUnu5HLNQrBdUY7pTo",
+	"f/8mYJ2dZ/9x3GHu2KHt+NdfX/1klowrs7PRXmpBC/NmzUWFVXaerUuOVbcBt9LhBijJ3Kh+jNTS/8kV",
+	"LuV48YRKQwPmB1VQybnN/OR6vKYMspt2OiwE3tu98IpfFJzEtyqbldJrCV76zeVZ6s1g2+0YebB+3zsK",
+	"gS0Ul7xR7+BTA1LpGXBZvlln579Nb7bltpt8CLkNXas5WP1M1+2cN3nWSLi4wmUJasycNd6jK6q2hg+l",
+	"4gJQIYBQhdZUSJWb5wUWBNV4L80voYdtd7vivATMDLAGAPgYgOC9wqqJEILmoaq2dOC6U6ZgYxFTCMAK",
+	"yAVWPSrVPHOkqKG/Eaa5ICDmIPTGNPKtLw7nJQFYcjaG49V2b4BTYqmQ2xRaY1oCiS1SKidtgDWVpq1P",
+	"DTSmaS14AVLqdppqiwKAmBftYDUwol9/jIzb1GQhwAY03sLDrzHvUORh28NLb84YE/QYd4z/Sr+LcmWS",
+	"l3uQ/zyzITNKv0/uZ00st27U5Er7mLfPEbH9SI4oQ0UjBLBiP5akeda+i22tPeymXl7IZlVRtZQxDidy",
+	"XgO7Ddct4KMa7ytgyvUYDdexWeSV5OVu4fJG/Ga3mMWAmuXZlaGSkksV5zK5YK+xszPgMj9WDyQd8zlA",
+	"tCQb0E+39ixE2QRRv3Ttg/OoT+Mh+U3qQYNNtf0mJn9NYzO2Z/+hSoAeanz+D4FsBptYTBICB3O5FvYO",
+	"Y16NNVhFqz0isMZNqYyGV5SNpDv4B2W00qSnRAN5VvmfJzMSosLXHgvP8xAnzyN02Weq0UFfwS12cDhr",
+	"DnDg2k0iQfKy8WK8jwfeqIJXPZadZMrhMea6T8yuNZKIQqIP8ItbCECzrqgSoweLvxnqncGrRSLGCqzI",
+	"OAOodLLG67FmbQl5F0CMX7GSY/KasssxxOC6pgLkImgJqDBl2mwkbmw5JtmSskurcuqFowIzJBUtS7QB",
+	"hdZcmFe14KQplD519U/LOwpdUUb4VZaPQJJnjSjnqVc3ysOtxeDykimqStAcNAZL0upafFTaHS7p0Yhi",
+	"i+VCCr7LqRacYt1qe+ecNxfDpcVgam0XAlUdFwzLDozWfI5YjBVIiTf3gaO0Dp0+iEIbbUT5AgpaUyeY",
+	"MdI2X67FsuaaEnFhGcLDO4BOsKfg5Pj65CTKg26SCzPsoM/Z1y/6Dpd/f//hg/z4n9/7P/6WTY65lJpG",
+	"APoFcKm2xn6MSWmlT8iLSkZNB9lam/7waOosz7SsmT87XOc8nORjcoHvoOYiwv6FXvhCDjR9TG9MCNW0",
+	"gMu3vVGnQBkCLAbQ+wFKsK92wTHovAayAfF3XGKnTw70rKIYGH4dGFZdpz5bEFhRhUqQ0jsptK0F1g3T",
+	"gpgy9c2LqOS3ncbjyqbyGpBtIpcOPGXTmVVPTmpaLJtzgB4Pzp55YCdut90BNo2vBLu5jqGt1vp98oxW",
+	"7v3hctnO9sp3nFXo2wX0Zkvv46V3RQ+Y8hb+pMOP3UvKSMheBa5VI6wVt24YMQyDxQZWuLiMGpY1l4qy",
+	"zVI4vrXd5s0ikrlVBlP1vDlpiHa4OthmCijcz9ZX04r9ffIZaJwv0JLudMa3k/WUnID5JlxMfaQtEo0p",
+	"UHsw5l6W1FxSRXeAMCOtUGOwwfrhnYF9iPSZAgBdCRzjz2XKnRsmpd8xuFYXRSMkF/NbSCtq4Sx/diV/",
+	"sOm76upvvKv97lL2/gNChwRKzAa0Jr5M0N+fDdL32Bwyd6fFLYxx4et0iNa9CHxA1ARfgSDKYujS7w6H",
+	"wD/xdQpL6QXbiNVFSuLVWLRuKzMIqjElNpYVxrF6ESxKIhGsqSBnq/YGsT//v936IBZo4DJc/OwR29Hh",
+	"mJlcFGSIMAJuK95SFEAAKusv0bRvQKFD6XhVAuKsgD56NTQSsfDF7pwpUzpiZt6X2ZhndtN3DQb4iFqe",
+	"4cKdj37g1glEWjUOyLy55KJOdqYkxu/DJd5GMpcdf/rEgd09HI9vrhiQt/YgkS+F4JEDYdLTovtfdCfR",
+	"4Tv3NDCp8vqpYxPFtvPWCuR/wWrL+eXLXdS1NxW0vMXZN6nVJgJkoX4xEwdYElyzDxKxg2fOoCG5jxs8",
+	"s0Hp7ndn33TPXMTh4ooz7boaPtZe5xxxtQUhERaA6IZxAeQDm9VfOgE2iJ6FKvm05O3j+x3IpowgnDR1",
+	"SQusIrDRMhV2xuWMJXLReyDaW4cZAixKCgIRKOkOxD6SN6HDeBPIigRCNC+UoPpZAT6u0/15Yb36FiXk",
+	"QiMly7OGFVvMNqahg/S8OGtX2K0nD4ASBazgFf+RE0jIhGR0Py0s4jLWt59cRDLe55cReD6/eZEvCn6a",
+	"IWKzv4Mdhat7VJL/CA1VUHl5QaCgkqYC8rqFlSn9KRPSpRt7QpdN64V3Dr53sYpWuxuqdL38lt7+Ynj2",
+	"6u2y5JZWFR+jmbmgcSzNCLSgtFovvrZ6XKcI6/Ujk5EntajFhABBnCHFa9ekikogBvFViqjIq0EUwJQ9",
+	"VqeVaL9NN5SdKUhm6LYaA6zBYcgdTRPRWfPs+mjDj0Zg/JV5JS6uWa+a/SJdMymxvIIYOTelQlRJZFrs",
+	"0apRiHGFKiwugaA9qPh5cAsNfHke24Np5XExHRzJLeA70MWw/y9jQyUjCIE7cmGOlZ7Ted7GNqVsk5Lt",
+	"+Mg3Rw0zkQdZ68N+BWsuwBqXnIHsmpm8Sftvlh8mf+1OX/p1HegMH+QC+d5pUP5oDOIDUl9ulbXyOFR7",
+	"WO5JK18mklAc0OOBgnvW8G8DmzvEHXy8xweABOxASNxmNGBFe0B5eISEkYchcmZ19AF7LEHWcsCnKGkm",
+	"FcSu8RcqFY/REzAl6AKPWUicd3Vt+7nHy74xyoa91FFwpnBh2d+eCNkKCyq/J7B7VvICl6PU2+wXKGtp",
+	"cxEURxaLCGtnm7bojKNJhx9qbOweqko9udGH0XsQO1oA+uHtqyzPNHnaIU+enT478VlTuKbZefb82cmz",
+	"5/b2x9YA7vhia0Ld+u+N1Vw0rA1VvyJmXea1BoOsOZMW8GcnJ5EYlVsHleiKi0stwC3mSgPor0+eT/bR",
+	"p/m4n3GQ6ijeb5lb6Uf9zK/7uHS63cTiX3s31NwGnMWpF4PNuLOzC8BkPzP9O9MmPr+mFOcWwbU1ASln",
+	"x787QWBJ+LBkBZc8YQixvy1clohADYwAKyhYz4AAXGy1OzNAzeOsRqEStDLHGXTL2mugm/SJNMxrQXdY",
+	"wTGu6fHu9NiZ5vL4MyU3SRz8DMpnumqyF7gCBUKaayP9hbnxbCoQtTqM2voQzrmVu50wsMf4YTBxytzH",
+	"ByQCv8kIxIl/lWcvTl6Myd5vXDPgmuvgeogDB/VDkHAc5jzXXEbQ8d5khg9yqJ8IZowu93dO9veNlGGu",
+	"+M3NzXC9N38MaXiEIQGFPmRIjgTUJS60EHb6OGqz+Z2mbgnpJBY53+GSku6a4QKK0y2/TbfEpRG1yN9Y",
+	"uDWJugFCCh3lC3JBpEsmsr6azo5hl98hjLT71a9sQ3dgW0OXwiqR9dtipF2yBorWdyjD3HBcXJpDXcCO",
+	"X9pXlXHV9nnmnV3y0xJjD8YsQSb70+ETD9SO/GZYQAS7eApMENLm8edGgrg5/uwCOJOHZ5iXPUN5elTU",
+	"NCnC06/vSnp5RGXTW5iY1rV4ymd3COMI8bVZ+1ssES6Miqp4mLGfpLFeV8ZTvQ8mIx0Ml8dN6w1MUo4O",
+	"x/adhnKOfCp8rT0iyFqcWuRuXDeD1U+Njfk4tJa0MvZ4B2F/z+b865PcD5adn56cBM6W00j2510xe1iA",
+	"tQeLiFtqhHWTYWEgYCSE7472oOZcZYfjszTpc8cu6Uwef3Z/3RyHaagp2dDLS57Fr50LdQluEWbtXqaZ",
+	"dWShDyfirNwjZ6m3ig3Cyrjx18q4/KlEzmcRI6614FWPtg7zdhy2EKtEza5B8eUreBRS7mH9EEr23l+n",
+	"RDkc+/TVGgTlJDc6Ug2iu+87d8TajknR17BLxq+Yn245UxQ+fztK/ia724JijvC/0ONDHJphkn2E6H7n",
+	"jWC4bLfrk6cN0SC1xQoRzr5SKEibP4jcDiYjo+RrZauZ8U8YL95PoLSncI6UdFN0Cfukeu8UoSer67gc",
+	"qzHC7NaIBUOKqW2j2zgqethobcJJ/cXixbf8y2MmvFueNoFk735zjnhJoFM87oS2PGGR8xqYRLi1uDnT",
+	"jnGfuBM4x2Uja2BE2jhxaI03TNESUYWo7KynoZ39pgZ2oJH9iNh+QEN7gTfq9DGtbFdQYk4iz/k1R1SX",
+	"MK/bNF7dNKSr1uA2hhcz6+pmvaXocQfT7IHw367dX0LuLFAsE4G6g0/4+5dL8zh1d9KSXu935v0bV/Hn",
+	"CeAzj9Wu6tL/rWdS9pL/E2qhaXLRNhmZaV1NqzENJTHik8ONVB8Jc+subatuCJW4roA2HKwj1js8XOWu",
+	"hxEYeVAPhAt32NgL54HnzvlNDqc0YTIfpzWVd67NYg+LJeO/hoslTBE9QHzYraMrTJXxn2hxjyrMGlwi",
+	"naaILOCHkmQp3ryIwHUt+G4iMvaDbWB38VSlfpJp3PYcU7Y1RgpXsg7hDabsfhmvjzkL7tvjR4DJ45gQ",
+	"4fr9nxQ7dnNAnhQCNJG0sYg2SCd9KaGZaL4tOfTHRyQewSKzW53Ql63z1itAeleu7OvKHuotFxq5VjiX",
+	"yaF4sodmiyl3ATrNKTY10mZdPRUE3b8RFUsDfWRLqpfYNqaOvipEd8C8GmTAOmdd2Y5L6MTe+5HH7vpQ",
+	"OsTeVkqz918kkvqXI1b/0meGeQ9p98ah01wSwkxegQDyHQJcbN3tIRpeHjK3Nbf8CnYg9Pm+N05O6fQz",
+	"d5nIXZEaivwC6A7615rmKFr914fm5OR50TB6bWdSuKrNM8h3p+7tFq7RL//44cej97/8cPb1N5p1P2T2",
+	"1aDPM/t0xcnePnDtWjftFrA1KxzP/M+RW/DRe7phuK2acWg45YH4JXYZ8JHj+9H7adGkGE1EHQUxfqUV",
+	"+sQttPlkGLNV3eo03Up6XBnbQf8CghTnqOS6mPeGJ49uzxWe/Ux2nzVM2JpuGgHp4xwzc1ew3Yw/Qlo2",
+	"WoHLxrSgyH1LRJ1Kh0qsQExICSccCiycICjB3oMZHBolYKHvTi0+MYb0f32kmxw9kn5lqgpLuW7Kco8K",
+	"vYmBcWc2rn2bKX3mqW/6ASy19B25MTPqfX0lTR50HK5xJeRXU0j5acE2j2Vn6H0YTuPNZqsQ9i6DHEnu",
+	"EzSCwo38ilkOx2XJr4AkTHc9QtxyX+NSQp7wztxO7C+6YV7h61e2/enJyTz69diIEokYADGJKytAOkHR",
+	"lNOwZHHgGTLBuK7s9qws96tJCmPTIGZG3U/sbFwfIKb38QrCS4yeXqwHylzd97qWVQTHXDUQ3MfegJhQ",
+	"+l2LJyjN7l+XGX6LIIIEUyRF3wcx1rE7o3Pjz+yXGrnaAkOrZm++2YEc3z6eTpQMx/b4w9Q3ucmzs5Oz",
+	"xwoEU4m2UBLvXAgdcn2nklXwnbfVeaG+sw5Y2ZoMVCIJSpVArMLSIsJO5n245qavLgMguyIvQ3tEQVlK",
+	"PUFucedX49hM7lmxFZzxRub9IjFOl9Kuau/Wbndib0+bLSjT6zW3QDVmieUPg3v/fCx6hmP1wyESHKkl",
+	"lpRNmQQ3s7aip3ajvYafofHVkDy8B1+robX/UA0iVOKNAHAYLxqpeAUCXW05qvBey31TRemDcyOeTdTl",
+	"hqKkbQTx+QSsCDDaicPQPZIQ8Oa4/oMFPBhV3Z5FDIVKRFLI60z0dtOBnyjs1BFDjtbcqhdDcmzxa0nI",
+	"WOBSdlU6tNHhy0b7+RzCzu5PdAzqYSRSZs1XbJCp7mSmctf3PbgKbLJ/wH0gie0rc7Vh/jA0407ZMu+g",
+	"4jtoF/kns2gCsAmzEXIwM0TV8yYe6yj3TxVAD+D7GJZOiaX7dmBX3BDsPiTX4b2cjqLhUfWF4ENU05tw",
+	"C8y7qLLU9/52oL1+YfZ5YNwE4kuikl5C33tNOEgv/SevXrRfazpUhD+qbAqA1MqgiICaEUU2E/+zHubm",
+	"2IaVp4JX+v3PVrl8qtaw3hFyFS4isQD3Jj1n+HmPs8fNRR0U2o8g3WzOR/9zq5t6+jeFuljLBl9p2rdF",
+	"XVP6S1uXkEqrLjk/XufYj9C96TAf4ovmK+TBHQRbA9Hq160m2ZbM0sOeniSG9SVKQtLWL/qkXXYlbZM3",
+	"D1yTp0vNvMafGlOcWWqVCV8CQzqNGwX1BuwXCWpt1/BGGyubVPK1bZ8tugYxzvZoKY7qDMqJ6SYyP84e",
+	"N/PjgJrFCRlrdxrRhLUBKBXyFXnDFLGJU8VACAXUFtKwp9geGbvcmtkMY/mnp2KBggKYj03UFsyPSNLx",
+	"axU+nch//9EGXNuqaPHUOfdy6d0mN5cr9TK4SeKnnrjK0daI0V3u617JYFHtrZIF67F97mFBkguFCBVQ",
+	"KHN7m9ll6b8nlqF7xSnEDJ/lbVUgbH6Zhx8fV9Poat1GpF5JTRl8h4pbiLSE5hA38cwsMZF36JWXpyz3",
+	"vtyzCfPBELc/poMHcwTRCyMkw6H9j9x+IZEHtOX7oI6rURvjnPVXNwPv9sifyH3m7SEUlcfpyROaGVvb",
+	"w43qTTqoCDVLcSaRIdTBxtWnQByZ9CPbdOA2Dz+D5/zq9qK182czm3akBGbSfGTqO9NFKgG4QuYu0qBa",
+	"/JW5dKj9at7nGMk8em/6GxZ+uXOfdPnCB9N8oOBaWXwfWfD3GWEU0xinyxmc8TXq4N7dSiZYYVS6Dy88",
+	"osikbMfdh+Unz9JXrt3/dzoZTUt40ZigkNMkExes/cuY8leTdaD72V9bVZV31/30WD0qbdXdFWU4LHHe",
+	"TpBbQjfzLyNwR0rt19n6FyjvSXC7SchCKldQufpM/humkyng4TdUv1B8oiQOJf1PEWsg/ykr24XYjgnu",
+	"rbb2dFoi8YmT+mu3bZEb/+UltKYlTLpUDa0wm3N5t3I7zGQbb3gv0tE5B5x2ocdHpoKzMeDlIDWk/V6p",
+	"jVHECkM50NgNf2q4wh7nfk5T5xFI+43fwUd9U3U5/cjxdNJp3na37CYY+JaXA55s1uN8ln5bLmUmTz+I",
+	"zffA7GAagfNxUA836WjsrgpQkF+85oscjA6+fxGneb+48hw5bm27/tUiBleDq9W39Zt3VN09HA7kqxvb",
+	"WrCUgZQdzO3DbIxGHafW9lfNKVNBBxtZHTW3hZQj7b1uNFpVCKVIv/am8bDjW5sdH+vjE+dvPt783wDd",
+	"218/PowAAA==",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -353,16 +2007,16 @@ var swaggerSpec = []string{
 func decodeSpec() ([]byte, error) {
 	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
 	if err != nil {
-		return nil, fmt.Errorf("error base64 decoding spec: %s", err)
+		return nil, fmt.Errorf("error base64 decoding spec: %w", err)
 	}
 	zr, err := gzip.NewReader(bytes.NewReader(zipped))
 	if err != nil {
-		return nil, fmt.Errorf("error decompressing spec: %s", err)
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
 	}
 	var buf bytes.Buffer
 	_, err = buf.ReadFrom(zr)
 	if err != nil {
-		return nil, fmt.Errorf("error decompressing spec: %s", err)
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
 	}
 
 	return buf.Bytes(), nil
@@ -380,7 +2034,7 @@ func decodeSpecCached() func() ([]byte, error) {
 
 // Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
 func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
-	var res = make(map[string]func() ([]byte, error))
+	res := make(map[string]func() ([]byte, error))
 	if len(pathToFile) > 0 {
 		res[pathToFile] = rawSpec
 	}
@@ -394,12 +2048,12 @@ func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
 // Externally referenced files must be embedded in the corresponding golang packages.
 // Urls can be supported but this task was out of the scope.
 func GetSwagger() (swagger *openapi3.T, err error) {
-	var resolvePath = PathToRawSpec("")
+	resolvePath := PathToRawSpec("")
 
 	loader := openapi3.NewLoader()
 	loader.IsExternalRefsAllowed = true
 	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
-		var pathToFile = url.String()
+		pathToFile := url.String()
 		pathToFile = path.Clean(pathToFile)
 		getSpec, ok := resolvePath[pathToFile]
 		if !ok {
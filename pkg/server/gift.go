@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"orderservice/pkg/repo/gift"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const defaultGiftLimit = 50
+
+func (h Handler) RedeemGift(ctx echo.Context, code string, params gen.RedeemGiftParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "RedeemGift", "request")
+	defer span.End()
+
+	g, err := h.giftStorage.Redeem(spanCtx, gift.NormalizeCode(code), params.XUserId)
+	switch {
+	case errors.Is(err, gift.ErrNotFound):
+		return ctx.NoContent(http.StatusNotFound)
+	case errors.Is(err, gift.ErrNotRecipient):
+		return ctx.NoContent(http.StatusForbidden)
+	case errors.Is(err, gift.ErrAlreadyRedeemed), errors.Is(err, gift.ErrNotRedeemable), errors.Is(err, gift.ErrDisputed):
+		return ctx.NoContent(http.StatusConflict)
+	case errors.Is(err, gift.ErrExpired):
+		return ctx.NoContent(http.StatusGone)
+	case err != nil:
+		h.log(ctx).Error("error on redeeming gift", zap.String("user_id", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	o, err := h.orderStorage.Get(spanCtx, g.OrderId)
+	if err != nil || o == nil {
+		h.log(ctx).Error("error on getting gift order", zap.String("order_id", g.OrderId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	h.log(ctx).Info("gift redeemed", zap.String("order_id", g.OrderId))
+	output := gen.GiftRedemption{
+		OrderId: g.OrderId,
+		Items:   toGenOrder(o).Items,
+	}
+	if g.Message != "" {
+		output.Message = &g.Message
+	}
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) ListUnredeemedGifts(ctx echo.Context, params gen.ListUnredeemedGiftsParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ListUnredeemedGifts", "request")
+	defer span.End()
+
+	limit := defaultGiftLimit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	gifts, err := h.giftStorage.ListUnredeemed(spanCtx, limit)
+	if err != nil {
+		h.log(ctx).Error("error on listing unredeemed gifts", zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	now := time.Now()
+	output := []gen.UnredeemedGift{}
+	for _, g := range gifts {
+		unredeemed := gen.UnredeemedGift{
+			Code:      g.Code,
+			OrderId:   g.OrderId,
+			BuyerId:   g.BuyerId,
+			ExpiresAt: g.ExpiresAt,
+			Expired:   g.Expired(now),
+		}
+		if g.RecipientEmail != "" {
+			email := g.RecipientEmail
+			unredeemed.RecipientEmail = &email
+		}
+		if g.RecipientUserId != "" {
+			userId := g.RecipientUserId
+			unredeemed.RecipientUserId = &userId
+		}
+		output = append(output, unredeemed)
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+// newGift sets up the gift of a new order, its code is redeemable once the
+// order is paid.
+func (h Handler) newGift(request *gen.GiftRequest) (*order.Gift, error) {
+	code, err := gift.NewCode()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &order.Gift{
+		Code:     code,
+		ValidFor: h.giftValidity,
+	}
+	if request.RecipientEmail != nil {
+		g.RecipientEmail = *request.RecipientEmail
+	}
+	if request.RecipientUserId != nil {
+		g.RecipientUserId = *request.RecipientUserId
+	}
+	if request.Message != nil {
+		g.Message = *request.Message
+	}
+	return g, nil
+}
+
+func toGenOrderGift(g *order.Gift) *gen.OrderGift {
+	output := &gen.OrderGift{
+		Code:       g.Code,
+		State:      gen.OrderGiftState(g.State),
+		ExpiresAt:  g.ExpiresAt,
+		RedeemedAt: g.RedeemedAt,
+	}
+	if g.State == gift.StateActive && g.ExpiresAt != nil && !time.Now().Before(*g.ExpiresAt) {
+		output.State = gen.OrderGiftStateExpired
+	}
+	if g.RecipientEmail != "" {
+		output.RecipientEmail = &g.RecipientEmail
+	}
+	if g.RecipientUserId != "" {
+		output.RecipientUserId = &g.RecipientUserId
+	}
+	if g.Message != "" {
+		output.Message = &g.Message
+	}
+	return output
+}
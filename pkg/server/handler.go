@@ -1,51 +1,197 @@
 package server
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"orderservice/pkg/checkout"
 	"orderservice/pkg/client/exchange"
 	"orderservice/pkg/client/payment"
 	"orderservice/pkg/client/product"
+	"orderservice/pkg/discount"
+	"orderservice/pkg/download"
+	"orderservice/pkg/events"
+	"orderservice/pkg/health"
+	"orderservice/pkg/invoice"
+	"orderservice/pkg/ledger"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/metrics"
 	"orderservice/pkg/repo/cart"
+	"orderservice/pkg/repo/dispute"
+	"orderservice/pkg/repo/entitlement"
+	"orderservice/pkg/repo/gift"
 	"orderservice/pkg/repo/order"
+	"orderservice/pkg/repo/wallet"
+	"orderservice/pkg/risk"
 	"orderservice/pkg/server/gen"
+	"orderservice/pkg/tax"
+	"orderservice/pkg/telemetry"
+	"orderservice/pkg/webhook"
 	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	"go.elastic.co/apm/v2"
 	"go.uber.org/zap"
 )
 
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
 type Handler struct {
-	logger         *zap.Logger
-	cartStorage    cart.CartStorage
-	orderStorage   order.OrderStorage
-	productClient  *product.ProductClient
-	exchangeClient *exchange.ExchangeClient
-	paymentClient  *payment.PaymentClient
+	logger             *zap.Logger
+	cartStorage        cart.CartStorage
+	orderStorage       order.OrderStorage
+	entitlementStorage entitlement.EntitlementStorage
+	promoStorage       discount.PromoStorage
+	invoiceStorage     invoice.InvoiceStorage
+	invoiceBuilder     *invoice.Builder
+	giftStorage        gift.GiftStorage
+	giftValidity       time.Duration
+	walletStorage      wallet.WalletStorage
+	ledgerStorage      ledger.LedgerStorage
+	productClient      *product.ProductClient
+	exchangeClient     *exchange.ExchangeClient
+	paymentClient      *payment.PaymentClient
+	healthChecker      *health.Checker
+	metrics            metrics.Metrics
+	riskEvaluator      risk.Evaluator
+	// nil when the risk check is disabled
+	fingerprinter   *risk.Fingerprinter
+	taxCalculator   tax.TaxCalculator
+	ipCountryHeader string
+	// nil when downloads are not configured
+	downloads *download.Issuer
+	// nil when checkouts are paid within the request
+	checkoutQueue checkout.Queue
+	sealer        *checkout.Sealer
+	broker        *events.Broker
+	// nil when payment webhooks are not configured
+	webhookVerifier *webhook.Verifier
+	webhookStorage  webhook.EventStorage
+	disputeStorage  dispute.DisputeStorage
+}
+
+// HandlerDeps are what the handler serves the requests with, the optional
+// ones are left nil when their feature is not configured.
+type HandlerDeps struct {
+	Logger             *zap.Logger
+	CartStorage        cart.CartStorage
+	OrderStorage       order.OrderStorage
+	EntitlementStorage entitlement.EntitlementStorage
+	PromoStorage       discount.PromoStorage
+	InvoiceStorage     invoice.InvoiceStorage
+	InvoiceBuilder     *invoice.Builder
+	GiftStorage        gift.GiftStorage
+	GiftValidity       time.Duration
+	WalletStorage      wallet.WalletStorage
+	LedgerStorage      ledger.LedgerStorage
+	ProductClient      *product.ProductClient
+	ExchangeClient     *exchange.ExchangeClient
+	PaymentClient      *payment.PaymentClient
+	HealthChecker      *health.Checker
+	Metrics            metrics.Metrics
+	RiskEvaluator      risk.Evaluator
+	// nil when the risk check is disabled
+	Fingerprinter   *risk.Fingerprinter
+	TaxCalculator   tax.TaxCalculator
+	IPCountryHeader string
+	// nil when downloads are not configured
+	Downloads *download.Issuer
+	// nil when checkouts are paid within the request
+	CheckoutQueue checkout.Queue
+	Sealer        *checkout.Sealer
+	Broker        *events.Broker
+	// nil when payment webhooks are not configured
+	WebhookVerifier *webhook.Verifier
+	WebhookStorage  webhook.EventStorage
+	DisputeStorage  dispute.DisputeStorage
 }
 
-func NewHandler(logger *zap.Logger, cartStorage cart.CartStorage, orderStorage order.OrderStorage, productClient *product.ProductClient, exchangeClient *exchange.ExchangeClient, paymentClient *payment.PaymentClient) Handler {
+func NewHandler(deps HandlerDeps) Handler {
 	return Handler{
-		logger:         logger,
-		cartStorage:    cartStorage,
-		orderStorage:   orderStorage,
-		productClient:  productClient,
-		exchangeClient: exchangeClient,
-		paymentClient:  paymentClient,
+		logger:             deps.Logger,
+		cartStorage:        deps.CartStorage,
+		orderStorage:       deps.OrderStorage,
+		entitlementStorage: deps.EntitlementStorage,
+		promoStorage:       deps.PromoStorage,
+		invoiceStorage:     deps.InvoiceStorage,
+		invoiceBuilder:     deps.InvoiceBuilder,
+		giftStorage:        deps.GiftStorage,
+		giftValidity:       deps.GiftValidity,
+		walletStorage:      deps.WalletStorage,
+		ledgerStorage:      deps.LedgerStorage,
+		productClient:      deps.ProductClient,
+		exchangeClient:     deps.ExchangeClient,
+		paymentClient:      deps.PaymentClient,
+		healthChecker:      deps.HealthChecker,
+		metrics:            deps.Metrics,
+		riskEvaluator:      deps.RiskEvaluator,
+		fingerprinter:      deps.Fingerprinter,
+		taxCalculator:      deps.TaxCalculator,
+		ipCountryHeader:    deps.IPCountryHeader,
+		downloads:          deps.Downloads,
+		checkoutQueue:      deps.CheckoutQueue,
+		sealer:             deps.Sealer,
+		broker:             deps.Broker,
+		webhookVerifier:    deps.WebhookVerifier,
+		webhookStorage:     deps.WebhookStorage,
+		disputeStorage:     deps.DisputeStorage,
 	}
 }
 
+// log returns the request scoped logger set up by RequestLogger.
+func (h Handler) log(ctx echo.Context) *zap.Logger {
+	return logging.FromContext(ctx.Request().Context())
+}
+
+// Health reports whether the dependencies of the service are up, the
+// details are only logged.
 func (h Handler) Health(ctx echo.Context) error {
+	report := h.healthChecker.Report(ctx.Request().Context())
+	if !report.Up() {
+		h.log(ctx).Warn("service is not healthy", zap.Any("checks", report.Checks))
+		return ctx.NoContent(http.StatusServiceUnavailable)
+	}
+	return ctx.NoContent(http.StatusOK)
+}
+
+func (h Handler) HealthLive(ctx echo.Context) error {
 	return ctx.NoContent(http.StatusOK)
 }
 
+func (h Handler) HealthReady(ctx echo.Context) error {
+	report := h.healthChecker.Report(ctx.Request().Context())
+
+	output := gen.HealthReport{
+		Status:    gen.HealthReportStatus(report.Status),
+		CheckedAt: report.CheckedAt,
+		Checks:    map[string]gen.HealthCheck{},
+	}
+	for name, result := range report.Checks {
+		// the errors may tell about the internals, they are only logged
+		output.Checks[name] = gen.HealthCheck{
+			Status:    gen.HealthCheckStatus(result.Status),
+			LatencyMs: float32(result.LatencyMs),
+		}
+	}
+
+	if !report.Up() {
+		h.log(ctx).Warn("service is not ready", zap.Any("checks", report.Checks))
+		return ctx.JSON(http.StatusServiceUnavailable, output)
+	}
+	return ctx.JSON(http.StatusOK, output)
+}
+
 func (h Handler) ClearCart(ctx echo.Context, params gen.ClearCartParams) error {
-	span, apmCtx := apm.StartSpan(ctx.Request().Context(), "ClearCart", "request")
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ClearCart", "request")
 	defer span.End()
 
-	err := h.cartStorage.Delete(apmCtx, params.XUserId)
+	err := h.cartStorage.Delete(spanCtx, params.XUserId)
 	if err != nil {
-		h.logger.Error("error on deleting key", zap.String("key", params.XUserId), zap.Error(err))
+		h.log(ctx).Error("error on deleting key", zap.String("key", params.XUserId), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
@@ -53,12 +199,12 @@ func (h Handler) ClearCart(ctx echo.Context, params gen.ClearCartParams) error {
 }
 
 func (h Handler) GetCart(ctx echo.Context, params gen.GetCartParams) error {
-	span, apmCtx := apm.StartSpan(ctx.Request().Context(), "GetCart", "request")
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetCart", "request")
 	defer span.End()
 
-	cart, err := h.cartStorage.Get(apmCtx, params.XUserId)
+	cart, err := h.cartStorage.Get(spanCtx, params.XUserId)
 	if err != nil {
-		h.logger.Error("error on getting key", zap.String("key", params.XUserId), zap.Error(err))
+		h.log(ctx).Error("error on getting key", zap.String("key", params.XUserId), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
@@ -81,61 +227,107 @@ func (h Handler) GetCart(ctx echo.Context, params gen.GetCartParams) error {
 }
 
 func (h Handler) UpdateCart(ctx echo.Context, params gen.UpdateCartParams) error {
-	span, apmCtx := apm.StartSpan(ctx.Request().Context(), "UpdateCart", "request")
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "UpdateCart", "request")
 	defer span.End()
 
 	ids := &[]string{}
 	if err := ctx.Bind(ids); err != nil {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
+	*ids = uniqueIds(*ids)
+
+	// digital products are bought once, unless they are a gift
+	if params.Gift == nil || !*params.Gift {
+		owned, err := h.entitlementStorage.Owned(spanCtx, params.XUserId, *ids)
+		if err != nil {
+			h.log(ctx).Error("error on getting owned products", zap.String("user_id", params.XUserId), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+		if len(owned) > 0 {
+			return ctx.JSON(http.StatusConflict, gen.OwnedProductsError{
+				Message:         "products are already owned",
+				OwnedProductIds: owned,
+			})
+		}
+	}
 
 	items := []cart.CartItem{}
 	for _, id := range *ids {
-		product, err := h.productClient.GetByUUID(apmCtx, id)
+		product, err := h.productClient.GetByUUID(spanCtx, id)
 		if err != nil {
-			h.logger.Error("error getting product detail", zap.String("user_id", params.XUserId), zap.String("product_id", id), zap.Error(err))
+			h.log(ctx).Error("error getting product detail", zap.String("user_id", params.XUserId), zap.String("product_id", id), zap.Error(err))
 			return ctx.NoContent(http.StatusInternalServerError)
 		}
 
 		price, err := strconv.ParseFloat(product.Price, 32)
 		if err != nil {
-			h.logger.Error("error casting product price to float32", zap.String("user_id", params.XUserId), zap.String("product_id", id), zap.String("price", product.Price), zap.Error(err))
+			h.log(ctx).Error("error casting product price to float32", zap.String("user_id", params.XUserId), zap.String("product_id", id), zap.String("price", product.Price), zap.Error(err))
 			return ctx.NoContent(http.StatusInternalServerError)
 		}
 		item := cart.CartItem{
-			Id:    id,
-			Name:  product.BookName,
-			Price: float32(price),
+			Id:     id,
+			Name:   product.BookName,
+			Author: product.Author,
+			Price:  float32(price),
 		}
 		items = append(items, item)
 	}
 
+	// the applied promo code stays, it is checked again at checkout
+	current, err := h.cartStorage.Get(spanCtx, params.XUserId)
+	if err != nil {
+		h.log(ctx).Error("error on getting key", zap.String("key", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
 	cart := cart.Cart{
 		Items: items,
 	}
+	if current != nil {
+		cart.PromoCode = current.PromoCode
+	}
 
-	err := h.cartStorage.Set(apmCtx, params.XUserId, &cart)
+	err = h.cartStorage.Set(spanCtx, params.XUserId, &cart)
 	if err != nil {
-		h.logger.Error("error on setting key", zap.String("key", params.XUserId), zap.Error(err))
+		h.log(ctx).Error("error on setting key", zap.String("key", params.XUserId), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
+	h.metrics.CartUpdated()
 
 	return ctx.NoContent(http.StatusOK)
 }
 
 func (h Handler) CheckoutCart(ctx echo.Context, params gen.CheckoutCartParams) error {
-	span, apmCtx := apm.StartSpan(ctx.Request().Context(), "CheckoutCart", "request")
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "CheckoutCart", "request")
 	defer span.End()
 
-	cardInfo := new(gen.CardInfo)
+	cardInfo := new(gen.CheckoutRequest)
 	if err := ctx.Bind(cardInfo); err != nil {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
+	giftRequest := cardInfo.Gift
+	if giftRequest != nil && giftRequest.RecipientEmail == nil && giftRequest.RecipientUserId == nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	h.metrics.CheckoutStarted()
+
+	// one checkout of the user at a time, the next one waits for it to end
+	if h.checkoutQueue != nil {
+		pending, err := h.checkoutQueue.Pending(spanCtx, params.XUserId)
+		if err != nil {
+			h.log(ctx).Error("error on getting pending checkout", zap.String("user_id", params.XUserId), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+		if pending != nil {
+			ctx.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/api/v1/orders/%s/checkout", pending.OrderId))
+			return ctx.NoContent(http.StatusConflict)
+		}
+	}
 
 	// get cart
-	cart, err := h.cartStorage.Get(apmCtx, params.XUserId)
+	cart, err := h.cartStorage.Get(spanCtx, params.XUserId)
 	if err != nil {
-		h.logger.Error("error on getting key", zap.String("key", params.XUserId), zap.Error(err))
+		h.log(ctx).Error("error on getting key", zap.String("key", params.XUserId), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
@@ -143,9 +335,33 @@ func (h Handler) CheckoutCart(ctx echo.Context, params gen.CheckoutCartParams) e
 		return ctx.NoContent(http.StatusNotFound)
 	}
 
+	// items bought since they were put in the cart are left out, what the
+	// buyer owns doesn't matter for a gift
+	cartIds := []string{}
+	for _, item := range cart.Items {
+		cartIds = append(cartIds, item.Id)
+	}
+	owned := []string{}
+	if giftRequest == nil {
+		owned, err = h.entitlementStorage.Owned(spanCtx, params.XUserId, cartIds)
+		if err != nil {
+			h.log(ctx).Error("error on getting owned products", zap.String("user_id", params.XUserId), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+	}
+	ownedSet := map[string]bool{}
+	for _, id := range owned {
+		ownedSet[id] = true
+	}
+
 	items := []order.Item{}
+	promoItems := []discount.Item{}
 	var total float32 = 0
 	for _, item := range cart.Items {
+		if ownedSet[item.Id] {
+			continue
+		}
+
 		orderItem := order.Item{
 			Id:    item.Id,
 			Name:  item.Name,
@@ -153,118 +369,242 @@ func (h Handler) CheckoutCart(ctx echo.Context, params gen.CheckoutCartParams) e
 		}
 
 		items = append(items, orderItem)
+		promoItems = append(promoItems, discount.Item{Id: item.Id, Author: item.Author, Price: item.Price})
 		total += item.Price
 	}
 
-	// create order with status ready
-	order, err := h.orderStorage.Create(apmCtx, params.XUserId, total, items)
-	if err != nil {
-		h.logger.Error("error during creating new order", zap.String("user_id", params.XUserId), zap.Error(err))
-		return ctx.NoContent(http.StatusInternalServerError)
+	if len(items) == 0 {
+		return ctx.JSON(http.StatusConflict, gen.OwnedProductsError{
+			Message:         "every product in the cart is already owned",
+			OwnedProductIds: owned,
+		})
+	}
+
+	// promo code
+	discounts := []order.Discount{}
+	if cart.PromoCode != "" {
+		line, err := h.evaluatePromo(spanCtx, params.XUserId, cart.PromoCode, promoItems)
+		if discount.IsRejected(err) {
+			return ctx.JSON(http.StatusUnprocessableEntity, gen.PromoCodeError{Code: cart.PromoCode, Message: err.Error()})
+		}
+		if err != nil {
+			h.log(ctx).Error("error on evaluating promo code", zap.String("user_id", params.XUserId), zap.String("code", cart.PromoCode), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		discounts = append(discounts, order.Discount{Code: line.Code, Description: line.Description, Amount: line.Amount})
+		total -= line.Amount
 	}
 
-	// exchange rate
-	exchangeResult, err := h.exchangeClient.GetTotal(apmCtx, "EUR", "USD", total)
+	// tax
+	evidence := h.taxEvidence(ctx, cardInfo.BillingCountry)
+	taxes, total, err := h.calculateTax(evidence, total)
+	if errors.Is(err, tax.ErrInsufficientEvidence) {
+		h.log(ctx).Info("checkout refused without tax evidence", zap.String("user_id", params.XUserId), zap.String("billing_country", evidence.BillingCountry), zap.String("ip_country", evidence.IPCountry))
+		return ctx.NoContent(http.StatusBadRequest)
+	}
 	if err != nil {
-		h.logger.Error("error exchange result", zap.String("user_id", params.XUserId), zap.Float32("total", total), zap.Error(err))
+		h.log(ctx).Error("error on calculating tax", zap.String("user_id", params.XUserId), zap.String("country", evidence.Country), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
-	// payment
-	paymentRequest := payment.PaymentRequest{
-		Amount:     float64(exchangeResult.Total),
-		Currency:   "USD",
-		CardNumber: cardInfo.Number,
-		ExpDate:    cardInfo.ExpDate,
-		CVV:        cardInfo.Cvv,
+	draft := order.Order{
+		UserId:      params.XUserId,
+		Total:       total,
+		Items:       items,
+		Discounts:   discounts,
+		Taxes:       taxes,
+		TaxEvidence: evidence,
+	}
+	if giftRequest != nil {
+		draft.Gift, err = h.newGift(giftRequest)
+		if err != nil {
+			h.log(ctx).Error("error on creating gift code", zap.String("user_id", params.XUserId), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
 	}
-	paymentResult, err := h.paymentClient.MakePayment(apmCtx, paymentRequest)
+
+	// an order approved after a risk review is paid as it is, otherwise a
+	// new order is created and goes through the risk check
+	order, err := h.findApprovedOrder(spanCtx, params.XUserId, items, giftRequest != nil, cardInfo.Number)
 	if err != nil {
-		h.logger.Error("error on payment result", zap.String("user_id", params.XUserId), zap.String("cvv", paymentRequest.CVV), zap.Error(err))
+		h.log(ctx).Error("error on finding approved order", zap.String("user_id", params.XUserId), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
-	// update order with status complete
-	if err := h.orderStorage.Complete(apmCtx, order.Id, paymentResult.Id); err != nil {
-		h.logger.Error("error during completing the order", zap.String("user_id", params.XUserId), zap.String("order_id", order.Id), zap.Error(err))
+	if order == nil {
+		// create order with status ready
+		order, err = h.orderStorage.Create(spanCtx, draft)
+		if err != nil {
+			h.log(ctx).Error("error during creating new order", zap.String("user_id", params.XUserId), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		// the use of the code is taken before payment, so that the last use
+		// of a limited code can't be paid twice
+		if cart.PromoCode != "" {
+			err := h.redeemPromo(ctx, spanCtx, cart.PromoCode, order)
+			if discount.IsRejected(err) {
+				return ctx.JSON(http.StatusUnprocessableEntity, gen.PromoCodeError{Code: cart.PromoCode, Message: err.Error()})
+			}
+			if err != nil {
+				h.log(ctx).Error("error on redeeming promo code", zap.String("user_id", params.XUserId), zap.String("order_id", order.Id), zap.Error(err))
+				return ctx.NoContent(http.StatusInternalServerError)
+			}
+		}
+
+		// risk check
+		assessment, err := h.assessRisk(ctx, spanCtx, order, cardInfo.Number)
+		// moved on by another request, which owns its promo code uses
+		if errors.Is(err, errAssessed) {
+			h.log(ctx).Warn("order assessed meanwhile", zap.String("user_id", params.XUserId), zap.String("order_id", order.Id))
+			return ctx.NoContent(http.StatusConflict)
+		}
+		if err != nil {
+			h.log(ctx).Error("error on assessing checkout risk", zap.String("user_id", params.XUserId), zap.String("order_id", order.Id), zap.Error(err))
+			h.releasePromo(spanCtx, order.Id)
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		switch assessment.Decision {
+		case risk.Deny:
+			h.log(ctx).Info("checkout denied", zap.String("order_id", order.Id), zap.Strings("reasons", assessment.Reasons))
+			h.releasePromo(spanCtx, order.Id)
+			return ctx.NoContent(http.StatusForbidden)
+		case risk.Review:
+			h.log(ctx).Info("checkout held for review", zap.String("order_id", order.Id), zap.Strings("reasons", assessment.Reasons))
+			return ctx.JSON(http.StatusAccepted, toGenOrder(order))
+		}
+	}
+
+	card := payment.Card{Number: cardInfo.Number, ExpDate: cardInfo.ExpDate, CVV: cardInfo.Cvv}
+	useWallet := cardInfo.UseWallet != nil && *cardInfo.UseWallet
+	if h.checkoutQueue != nil {
+		return h.enqueueCheckout(ctx, spanCtx, order, card, useWallet)
+	}
+
+	err = h.pay(spanCtx, order, card, useWallet)
+	if errors.Is(err, payment.ErrPaymentDeclined) {
+		return ctx.NoContent(http.StatusPaymentRequired)
+	}
+	// the order waits for the payment webhook, the cart is kept until then
+	if errors.Is(err, payment.ErrPaymentPending) {
+		return ctx.JSON(http.StatusAccepted, toGenOrder(order))
+	}
+	if err != nil {
+		// nothing retries the order, the next checkout creates another one
+		h.releasePromo(spanCtx, order.Id)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
 	// get updated order
-	order, err = h.orderStorage.Get(apmCtx, order.Id)
+	order, err = h.orderStorage.Get(spanCtx, order.Id)
 	if err != nil {
-		h.logger.Error("error getting created order", zap.String("user_id", params.XUserId), zap.String("order_id", order.Id), zap.Error(err))
+		h.log(ctx).Error("error getting created order", zap.String("user_id", params.XUserId), zap.String("order_id", order.Id), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
 	// clear cart
-	err = h.cartStorage.Delete(apmCtx, params.XUserId)
+	err = h.cartStorage.Delete(spanCtx, params.XUserId)
 	if err != nil {
-		h.logger.Error("error on clearing user's cart", zap.String("user_id", params.XUserId), zap.Error(err))
+		h.log(ctx).Error("error on clearing user's cart", zap.String("user_id", params.XUserId), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
 	// representation
-	output := gen.Order{
-		Id:        order.Id,
-		PaymentId: order.PaymentId,
-		Status:    order.Status,
-		Total:     order.Total,
-		Items:     []gen.CartItem{},
+	return ctx.JSON(http.StatusOK, toGenOrder(order))
+}
+
+func (h Handler) ListOrders(ctx echo.Context, params gen.ListOrdersParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ListOrders", "request")
+	defer span.End()
+
+	opts := order.ListOptions{
+		Limit: defaultListLimit,
+		Sort:  order.SortDesc,
 	}
-	for _, item := range order.Items {
-		genItem := gen.CartItem{
-			Id:    item.Id,
-			Name:  item.Name,
-			Price: item.Price,
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxListLimit {
+			return ctx.NoContent(http.StatusBadRequest)
 		}
-		output.Items = append(output.Items, genItem)
+		opts.Limit = *params.Limit
+	}
+	if params.Sort != nil {
+		if *params.Sort != gen.Asc && *params.Sort != gen.Desc {
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		opts.Sort = order.SortDirection(*params.Sort)
+	}
+	if params.Cursor != nil {
+		cursor, err := order.DecodeCursor(*params.Cursor)
+		if err != nil {
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		opts.Cursor = cursor
+	}
+	if params.Status != nil {
+		opts.Status = *params.Status
+	}
+	opts.CreatedAfter = params.CreatedAfter
+	opts.CreatedBefore = params.CreatedBefore
+
+	page, err := h.orderStorage.List(spanCtx, params.XUserId, opts)
+	if err != nil {
+		h.log(ctx).Error("error on listing orders for user", zap.String("key", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := gen.OrderList{
+		Items: []gen.Order{},
+	}
+	for i := range page.Orders {
+		output.Items = append(output.Items, toGenOrder(&page.Orders[i]))
+	}
+	if page.NextCursor != nil {
+		next := page.NextCursor.Encode()
+		output.NextCursor = &next
+	}
+	if page.PrevCursor != nil {
+		prev := page.PrevCursor.Encode()
+		output.PrevCursor = &prev
 	}
 
 	return ctx.JSON(http.StatusOK, output)
 }
 
-func (h Handler) ListOrders(ctx echo.Context, params gen.ListOrdersParams) error {
-	span, apmCtx := apm.StartSpan(ctx.Request().Context(), "ListOrders", "request")
+func (h Handler) GetOrder(ctx echo.Context, uuid string, params gen.GetOrderParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetOrder", "request")
 	defer span.End()
 
-	orders, err := h.orderStorage.List(apmCtx, params.XUserId)
+	if !isUUID(uuid) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	order, err := h.orderStorage.Get(spanCtx, uuid)
 	if err != nil {
-		h.logger.Error("error on listing orders for user", zap.String("key", params.XUserId), zap.Error(err))
+		h.log(ctx).Error("error on getting order by id", zap.String("user_id", params.XUserId), zap.String("order_id", uuid), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
-	outputOrders := []gen.Order{}
-	for _, order := range orders {
-		outputOrder := gen.Order{
-			Id:        order.Id,
-			PaymentId: order.PaymentId,
-			Status:    order.Status,
-			Total:     order.Total,
-			Items:     []gen.CartItem{},
-		}
-
-		for _, item := range order.Items {
-			genItem := gen.CartItem{
-				Id:    item.Id,
-				Name:  item.Name,
-				Price: item.Price,
-			}
-			outputOrder.Items = append(outputOrder.Items, genItem)
-		}
-
-		outputOrders = append(outputOrders, outputOrder)
+	// orders of other users are reported as missing so their ids can't be probed
+	if order == nil || order.UserId != params.XUserId {
+		return ctx.NoContent(http.StatusNotFound)
 	}
-	return ctx.JSON(http.StatusOK, outputOrders)
+
+	return ctx.JSON(http.StatusOK, toGenOrder(order))
 }
 
 func (h Handler) GetOrderDetail(ctx echo.Context, uuid string) error {
-	span, apmCtx := apm.StartSpan(ctx.Request().Context(), "GetOrderDetail", "request")
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetOrderDetail", "request")
 	defer span.End()
 
-	order, err := h.orderStorage.Get(apmCtx, uuid)
+	if !isUUID(uuid) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	order, err := h.orderStorage.Get(spanCtx, uuid)
 	if err != nil {
-		h.logger.Error("error on getting order by id", zap.String("order_id", uuid), zap.Error(err))
+		h.log(ctx).Error("error on getting order by id", zap.String("order_id", uuid), zap.Error(err))
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 
@@ -272,12 +612,95 @@ func (h Handler) GetOrderDetail(ctx echo.Context, uuid string) error {
 		return ctx.NoContent(http.StatusNotFound)
 	}
 
+	return ctx.JSON(http.StatusOK, toGenOrder(order))
+}
+
+func (h Handler) RefundOrder(ctx echo.Context, uuid string, params gen.RefundOrderParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "RefundOrder", "request")
+	defer span.End()
+
+	if !isUUID(uuid) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	o, err := h.orderStorage.Get(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting order by id", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if o == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+	if o.Status != order.StatusCompleted || (o.Gift != nil && o.Gift.State == gift.StateRedeemed) {
+		return ctx.NoContent(http.StatusConflict)
+	}
+	disputed, err := h.disputeStorage.FindOpen(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on finding open dispute", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if disputed != nil {
+		return ctx.NoContent(http.StatusConflict)
+	}
+
+	// the card is paid back before the order is marked refunded, the ledger
+	// never shows money returned that didn't move. Refunding the payment
+	// again is harmless, a failed request is simply retried
+	storeCredit := params.StoreCredit != nil && *params.StoreCredit
+	if !storeCredit && o.PaymentId != nil {
+		if err := h.paymentClient.RefundPayment(spanCtx, *o.PaymentId); err != nil {
+			h.log(ctx).Error("error on refunding payment", zap.String("order_id", uuid), zap.String("payment_id", *o.PaymentId), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+	}
+
+	refunded, err := h.orderStorage.Refund(spanCtx, uuid, storeCredit)
+	if err == nil && !refunded {
+		err = errors.New("order moved while refunding it")
+	}
+	if err != nil {
+		h.log(ctx).Error("error on refunding order", zap.String("order_id", uuid), zap.Bool("card_refunded", !storeCredit && o.PaymentId != nil), zap.Error(err))
+		if errors.Is(err, order.ErrGiftRedeemed) {
+			return ctx.NoContent(http.StatusConflict)
+		}
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	// the store credit spent on the order goes back to the wallet, and the
+	// rest too when the refund is paid as store credit
+	if storeCredit {
+		_, err = h.walletStorage.Credit(spanCtx, wallet.Credit{UserId: o.UserId, Amount: o.Total, Reason: "order refunded", OrderId: &o.Id})
+		if err != nil {
+			h.log(ctx).Error("error on crediting refund", zap.String("order_id", uuid), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+	} else if _, err := h.walletStorage.Reverse(spanCtx, uuid, "order refunded"); err != nil {
+		h.log(ctx).Error("error on reversing wallet debit", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	h.log(ctx).Info("order refunded", zap.String("order_id", uuid))
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func toGenOrder(order *order.Order) gen.Order {
 	output := gen.Order{
 		Id:        order.Id,
 		PaymentId: order.PaymentId,
 		Status:    order.Status,
+		Subtotal:  order.Subtotal,
 		Total:     order.Total,
 		Items:     []gen.CartItem{},
+		Discounts: []gen.DiscountLine{},
+		Taxes:     []gen.TaxLine{},
+		CreatedAt: order.CreatedAt,
+	}
+	// copied, the order may be a loop variable of the caller
+	if country := order.TaxEvidence.Country; country != "" {
+		output.TaxCountry = &country
+	}
+	if order.Gift != nil {
+		output.Gift = toGenOrderGift(order.Gift)
 	}
 	for _, item := range order.Items {
 		genItem := gen.CartItem{
@@ -287,6 +710,41 @@ func (h Handler) GetOrderDetail(ctx echo.Context, uuid string) error {
 		}
 		output.Items = append(output.Items, genItem)
 	}
+	for _, d := range order.Discounts {
+		output.Discounts = append(output.Discounts, gen.DiscountLine{
+			Code:        d.Code,
+			Description: d.Description,
+			Amount:      d.Amount,
+		})
+	}
+	for _, t := range order.Taxes {
+		output.Taxes = append(output.Taxes, gen.TaxLine{
+			Country:   t.Country,
+			Rate:      t.Rate,
+			Net:       t.Net,
+			Amount:    t.Amount,
+			Inclusive: t.Inclusive,
+		})
+	}
 
-	return ctx.JSON(http.StatusOK, output)
+	return output
+}
+
+func isUUID(value string) bool {
+	_, err := uuid.Parse(value)
+	return err == nil
+}
+
+// uniqueIds drops repeated ids keeping the first occurrence.
+func uniqueIds(ids []string) []string {
+	seen := map[string]bool{}
+	output := []string{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		output = append(output, id)
+	}
+	return output
 }
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"orderservice/pkg/invoice"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (h Handler) GetOrderInvoice(ctx echo.Context, uuid string, params gen.GetOrderInvoiceParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetOrderInvoice", "request")
+	defer span.End()
+
+	if !isUUID(uuid) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	o, err := h.orderStorage.Get(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting order by id", zap.String("user_id", params.XUserId), zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if o == nil || o.UserId != params.XUserId {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	record, err := h.invoiceStorage.Get(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting invoice", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if record == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	inv := h.invoiceBuilder.Build(record, o)
+
+	buf := new(bytes.Buffer)
+	contentType := "application/pdf"
+	render := invoice.RenderPDF
+	if params.Format != nil && *params.Format == gen.Html {
+		contentType = "text/html; charset=utf-8"
+		render = invoice.RenderHTML
+	}
+	if err := render(buf, inv); err != nil {
+		h.log(ctx).Error("error on rendering invoice", zap.String("order_id", uuid), zap.String("number", inv.Number), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	if contentType == "application/pdf" {
+		ctx.Response().Header().Set(echo.HeaderContentDisposition, `inline; filename="`+inv.Number+`.pdf"`)
+	}
+	return ctx.Blob(http.StatusOK, contentType, buf.Bytes())
+}
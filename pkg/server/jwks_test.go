@@ -0,0 +1,72 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseJWKS(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(b []byte) string {
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	ec := jwk{Kid: "ec", Kty: "EC", Crv: "P-256", X: encode(private.X.Bytes()), Y: encode(private.Y.Bytes())}
+	rsa := jwk{Kid: "rsa", Kty: "RSA", N: encode([]byte{0xc3, 0x5f, 0x11}), E: "AQAB"}
+	okp := jwk{Kid: "okp", Kty: "OKP", Crv: "Ed25519", X: encode(make([]byte, 32))}
+	p384 := jwk{Kid: "p384", Kty: "EC", Crv: "P-384", X: "AA", Y: "AA"}
+	offCurve := jwk{Kid: "off", Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}
+	encryption := ec
+	encryption.Kid, encryption.Use = "enc", "enc"
+
+	tests := []struct {
+		name     string
+		keys     []jwk
+		wantKids []string
+		wantErr  bool
+	}{
+		{"supported keys", []jwk{ec, rsa}, []string{"ec", "rsa"}, false},
+		{"unsupported keys skipped", []jwk{okp, ec, p384}, []string{"ec"}, false},
+		{"invalid key skipped", []jwk{offCurve, rsa}, []string{"rsa"}, false},
+		{"encryption keys left out", []jwk{encryption, rsa}, []string{"rsa"}, false},
+		{"no usable key", []jwk{okp, p384}, nil, true},
+		{"no key", []jwk{}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(jwkSet{Keys: tt.keys})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			keys, err := parseJWKS(data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseJWKS() = %v, want an error", keys)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJWKS() = %v", err)
+			}
+
+			kids := []string{}
+			for kid := range keys {
+				kids = append(kids, kid)
+			}
+			sort.Strings(kids)
+			if strings.Join(kids, ",") != strings.Join(tt.wantKids, ",") {
+				t.Errorf("parseJWKS() keys %v, want %v", kids, tt.wantKids)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"orderservice/pkg/ledger"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (h Handler) GetOrderJournal(ctx echo.Context, uuid string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetOrderJournal", "request")
+	defer span.End()
+
+	if !isUUID(uuid) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	o, err := h.orderStorage.Get(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting order by id", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if o == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	entries, err := h.ledgerStorage.Entries(spanCtx, uuid)
+	if err != nil {
+		h.log(ctx).Error("error on getting journal of order", zap.String("order_id", uuid), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := []gen.LedgerEntry{}
+	for _, e := range entries {
+		entry := gen.LedgerEntry{
+			Id:        e.Id,
+			Kind:      gen.LedgerEntryKind(e.Kind),
+			Postings:  []gen.LedgerPosting{},
+			CreatedAt: e.CreatedAt,
+		}
+		for _, p := range e.Postings {
+			entry.Postings = append(entry.Postings, gen.LedgerPosting{Account: p.Account, Currency: p.Currency, Amount: p.Amount})
+		}
+		output = append(output, entry)
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) GetLedgerBalances(ctx echo.Context, account string, params gen.GetLedgerBalancesParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetLedgerBalances", "request")
+	defer span.End()
+
+	known := false
+	for _, a := range ledger.Accounts {
+		known = known || a == account
+	}
+	if !known {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	period, ok := ledgerPeriod(params.From, params.To)
+	if !ok {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	balances, err := h.ledgerStorage.Balances(spanCtx, account, period)
+	if err != nil {
+		h.log(ctx).Error("error on getting ledger balances", zap.String("account", account), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := []gen.LedgerBalance{}
+	for _, b := range balances {
+		output = append(output, gen.LedgerBalance{
+			Account:  b.Account,
+			Currency: b.Currency,
+			Debit:    b.Debit,
+			Credit:   b.Credit,
+			Balance:  b.Net(),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) CheckLedger(ctx echo.Context, params gen.CheckLedgerParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "CheckLedger", "request")
+	defer span.End()
+
+	period, ok := ledgerPeriod(params.From, params.To)
+	if !ok {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	imbalances, err := h.ledgerStorage.Check(spanCtx, period)
+	if err != nil {
+		h.log(ctx).Error("error on checking ledger", zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := gen.LedgerCheck{
+		Balanced:   len(imbalances) == 0,
+		Imbalances: []gen.LedgerImbalance{},
+	}
+	for _, i := range imbalances {
+		output.Imbalances = append(output.Imbalances, gen.LedgerImbalance{
+			EntryId:  i.EntryId,
+			OrderId:  i.OrderId,
+			Currency: i.Currency,
+			Amount:   i.Amount,
+		})
+	}
+	if !output.Balanced {
+		h.log(ctx).Warn("ledger does not balance", zap.Int("entries", len(imbalances)))
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+// ledgerPeriod builds the period between from and to, which must not end
+// before it starts.
+func ledgerPeriod(from *time.Time, to *time.Time) (ledger.Period, bool) {
+	if from != nil && to != nil && to.Before(*from) {
+		return ledger.Period{}, false
+	}
+	return ledger.Period{From: from, To: to}, true
+}
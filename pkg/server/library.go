@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"orderservice/pkg/repo/entitlement"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (h Handler) GetLibrary(ctx echo.Context, params gen.GetLibraryParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetLibrary", "request")
+	defer span.End()
+
+	opts := entitlement.ListOptions{
+		Limit: defaultListLimit,
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxListLimit {
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		opts.Limit = *params.Limit
+	}
+	if params.Cursor != nil {
+		cursor, err := entitlement.DecodeCursor(*params.Cursor)
+		if err != nil {
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		opts.Cursor = cursor
+	}
+
+	page, err := h.entitlementStorage.List(spanCtx, params.XUserId, opts)
+	if err != nil {
+		h.log(ctx).Error("error on listing entitlements for user", zap.String("user_id", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := gen.Library{
+		Items: []gen.LibraryItem{},
+	}
+	for _, e := range page.Entitlements {
+		output.Items = append(output.Items, gen.LibraryItem{
+			ProductId:   e.ProductId,
+			OrderId:     e.OrderId,
+			Name:        e.Name,
+			PurchasedAt: e.PurchasedAt,
+		})
+	}
+	if page.NextCursor != nil {
+		next := page.NextCursor.Encode()
+		output.NextCursor = &next
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) GetEntitlement(ctx echo.Context, user string, product string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetEntitlement", "request")
+	defer span.End()
+
+	if !isUUID(user) || !isUUID(product) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	e, err := h.entitlementStorage.Get(spanCtx, user, product)
+	if err != nil {
+		h.log(ctx).Error("error on getting entitlement", zap.String("user_id", user), zap.String("product_id", product), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	if e == nil || !e.Active() {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	return ctx.JSON(http.StatusOK, gen.Entitlement{
+		UserId:      e.UserId,
+		ProductId:   e.ProductId,
+		OrderId:     e.OrderId,
+		Name:        e.Name,
+		PurchasedAt: e.PurchasedAt,
+	})
+}
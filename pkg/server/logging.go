@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/telemetry"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// RequestLogger assigns a request id (or keeps the one set by the caller),
+// puts a logger with the request id, trace id, route and user id into the
+// request context and writes an access log line once the request is done.
+// It has to run after the tracing middleware to see the trace id.
+func RequestLogger(logger *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			requestId := req.Header.Get(logging.RequestIdHeader)
+			if requestId == "" {
+				requestId = uuid.NewString()
+			}
+			c.Response().Header().Set(logging.RequestIdHeader, requestId)
+
+			fields := []zap.Field{
+				zap.String("request_id", requestId),
+				zap.String("route", c.Path()),
+			}
+			if traceId := telemetry.TraceId(req.Context()); traceId != "" {
+				fields = append(fields, zap.String("trace_id", traceId))
+			}
+			if userId := req.Header.Get(userIdHeader); userId != "" {
+				fields = append(fields, zap.String("user_id", userId))
+			}
+			requestLogger := logger.With(fields...)
+
+			ctx := logging.WithRequestId(req.Context(), requestId)
+			ctx = logging.WithLogger(ctx, requestLogger)
+			c.SetRequest(req.WithContext(ctx))
+
+			start := time.Now()
+			err := next(c)
+
+			requestLogger.Info("request handled",
+				zap.String("method", req.Method),
+				zap.String("path", req.URL.Path),
+				zap.Int("status", responseStatus(c, err)),
+				zap.Duration("latency", time.Since(start)),
+				zap.Int64("bytes_out", c.Response().Size),
+			)
+			return err
+		}
+	}
+}
+
+// responseStatus is the status the client gets, including errors returned
+// to the echo error handler which hasn't written them yet.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"net/http"
+	"orderservice/pkg/metrics"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequestMetrics records rate, errors and duration of every request by its
+// route template so that path parameters don't blow up the label space.
+func RequestMetrics(m metrics.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := responseStatus(c, err)
+
+			route := c.Path()
+			if route == "" || status == http.StatusNotFound && route == "/*" {
+				route = "unmatched"
+			}
+
+			m.ObserveHTTPRequest(c.Request().Method, route, status, time.Since(start))
+			return err
+		}
+	}
+}
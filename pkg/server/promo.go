@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"orderservice/pkg/discount"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/repo/cart"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (h Handler) ApplyPromoCode(ctx echo.Context, params gen.ApplyPromoCodeParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ApplyPromoCode", "request")
+	defer span.End()
+
+	request := new(gen.PromoCodeRequest)
+	if err := ctx.Bind(request); err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	code := discount.NormalizeCode(request.Code)
+
+	c, err := h.cartStorage.Get(spanCtx, params.XUserId)
+	if err != nil {
+		h.log(ctx).Error("error on getting key", zap.String("key", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if c == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	// priced like the checkout does, without the products the user owns
+	cartIds := []string{}
+	for _, item := range c.Items {
+		cartIds = append(cartIds, item.Id)
+	}
+	owned, err := h.entitlementStorage.Owned(spanCtx, params.XUserId, cartIds)
+	if err != nil {
+		h.log(ctx).Error("error on getting owned products", zap.String("user_id", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	ownedSet := map[string]bool{}
+	for _, id := range owned {
+		ownedSet[id] = true
+	}
+	items := []cart.CartItem{}
+	for _, item := range c.Items {
+		if !ownedSet[item.Id] {
+			items = append(items, item)
+		}
+	}
+
+	line, err := h.evaluatePromo(spanCtx, params.XUserId, code, promoItems(items))
+	if discount.IsRejected(err) {
+		return ctx.JSON(http.StatusUnprocessableEntity, gen.PromoCodeError{Code: code, Message: err.Error()})
+	}
+	if err != nil {
+		h.log(ctx).Error("error on evaluating promo code", zap.String("user_id", params.XUserId), zap.String("code", code), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	c.PromoCode = code
+	if err := h.cartStorage.Set(spanCtx, params.XUserId, c); err != nil {
+		h.log(ctx).Error("error on setting key", zap.String("key", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	var subtotal float32
+	for _, item := range items {
+		subtotal += item.Price
+	}
+
+	return ctx.JSON(http.StatusOK, gen.CartTotals{
+		PromoCode: &code,
+		Subtotal:  subtotal,
+		Discounts: []gen.DiscountLine{{Code: line.Code, Description: line.Description, Amount: line.Amount}},
+		Total:     subtotal - line.Amount,
+	})
+}
+
+func (h Handler) RemovePromoCode(ctx echo.Context, params gen.RemovePromoCodeParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "RemovePromoCode", "request")
+	defer span.End()
+
+	c, err := h.cartStorage.Get(spanCtx, params.XUserId)
+	if err != nil {
+		h.log(ctx).Error("error on getting key", zap.String("key", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if c == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	c.PromoCode = ""
+	if err := h.cartStorage.Set(spanCtx, params.XUserId, c); err != nil {
+		h.log(ctx).Error("error on setting key", zap.String("key", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// evaluatePromo computes the discount of code on the cart items of the
+// user, failing with an error accepted by discount.IsRejected when the code
+// can't be used.
+func (h Handler) evaluatePromo(spanCtx context.Context, userId string, code string, items []discount.Item) (*discount.Line, error) {
+	p, err := h.promoStorage.Get(spanCtx, code)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, discount.ErrCodeNotFound
+	}
+
+	total, byUser, err := h.promoStorage.Uses(spanCtx, code, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := discount.CheckLimits(p, total, byUser); err != nil {
+		return nil, err
+	}
+
+	return p.Apply(items, time.Now())
+}
+
+// redeemPromo takes a use of code for the new order. An order whose code
+// can't be used anymore is rejected.
+func (h Handler) redeemPromo(ctx echo.Context, spanCtx context.Context, code string, o *order.Order) error {
+	err := h.promoStorage.Redeem(spanCtx, code, o.UserId, o.Id)
+	if discount.IsRejected(err) {
+		if _, err := h.orderStorage.UpdateStatus(spanCtx, o.Id, order.StatusReady, order.StatusRejected); err != nil {
+			h.log(ctx).Error("error on rejecting order", zap.String("order_id", o.Id), zap.Error(err))
+		}
+	}
+	return err
+}
+
+func promoItems(items []cart.CartItem) []discount.Item {
+	output := []discount.Item{}
+	for _, item := range items {
+		output = append(output, discount.Item{Id: item.Id, Author: item.Author, Price: item.Price})
+	}
+	return output
+}
+
+// releasePromo gives back the promo code uses of an order that won't be
+// paid. Failures are only logged, the order is not paid either way.
+func (h Handler) releasePromo(spanCtx context.Context, orderId string) {
+	if err := h.promoStorage.Release(spanCtx, orderId); err != nil {
+		logging.FromContext(spanCtx).Error("error on releasing promo code", zap.String("order_id", orderId), zap.Error(err))
+	}
+}
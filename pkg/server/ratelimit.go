@@ -0,0 +1,100 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/ratelimit"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
+)
+
+type RateLimitConfig struct {
+	Skipper middleware.Skipper
+
+	Limiter  ratelimit.Limiter
+	Policies []ratelimit.Policy
+}
+
+// RateLimit applies every policy matching the route. The RateLimit-*
+// headers describe the policy closest to its limit. Requests are let
+// through when the limiter backend fails.
+func RateLimit(config RateLimitConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			route := c.Path()
+
+			var tightest *ratelimit.Result
+			for i, policy := range config.Policies {
+				if !policy.Matches(req.Method, route) {
+					continue
+				}
+
+				key := strconv.Itoa(i) + ":" + rateLimitKey(c, policy)
+				result, err := config.Limiter.Allow(req.Context(), key, policy.Limit, policy.Window)
+				if err != nil {
+					logging.FromContext(req.Context()).Warn("rate limiter failed", zap.String("policy", policy.Route), zap.Error(err))
+					continue
+				}
+
+				if tightest == nil || !result.Allowed || (tightest.Allowed && result.Remaining < tightest.Remaining) {
+					tightest = &result
+				}
+				if !result.Allowed {
+					break
+				}
+			}
+
+			if tightest == nil {
+				return next(c)
+			}
+
+			reset := strconv.Itoa(int(math.Ceil(tightest.ResetAfter.Seconds())))
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+			header.Set("RateLimit-Reset", reset)
+
+			if !tightest.Allowed {
+				header.Set("Retry-After", reset)
+				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+func rateLimitKey(c echo.Context, policy ratelimit.Policy) string {
+	switch policy.KeyBy {
+	case ratelimit.KeyByGlobal:
+		return "global"
+	case ratelimit.KeyByUser:
+		// only the user authenticated by JWTAuth, a header can be made up
+		// for every request
+		if userId, ok := c.Get(userIdContextKey).(string); ok && userId != "" {
+			return "user:" + userId
+		}
+	}
+	// the ip is only taken from the forwarding headers set by the trusted
+	// proxies, see ipExtractor
+	return "ip:" + c.RealIP()
+}
+
+// probeSkipper skips health probes and metric scrapes.
+func probeSkipper(c echo.Context) bool {
+	path := c.Request().URL.Path
+	return strings.HasPrefix(path, "/_health") || path == "/metrics"
+}
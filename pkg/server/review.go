@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/risk"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const defaultReviewLimit = 50
+
+func (h Handler) ListReviews(ctx echo.Context, params gen.ListReviewsParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ListReviews", "request")
+	defer span.End()
+
+	limit := defaultReviewLimit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	orders, err := h.orderStorage.ListByStatus(spanCtx, order.StatusReview, limit)
+	if err != nil {
+		h.log(ctx).Error("error on listing orders in review", zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := []gen.ReviewOrder{}
+	for _, o := range orders {
+		reviewOrder := gen.ReviewOrder{
+			Id:           o.Id,
+			UserId:       o.UserId,
+			Status:       o.Status,
+			Total:        o.Total,
+			Items:        toGenOrder(&o).Items,
+			CreatedAt:    o.CreatedAt,
+			RiskDecision: o.RiskDecision,
+			RiskReasons:  o.RiskReasons,
+		}
+		if reviewOrder.RiskReasons == nil {
+			reviewOrder.RiskReasons = []string{}
+		}
+		output = append(output, reviewOrder)
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) ApproveReview(ctx echo.Context, uuid string) error {
+	return h.resolveReview(ctx, "ApproveReview", uuid, order.StatusApproved)
+}
+
+func (h Handler) RejectReview(ctx echo.Context, uuid string) error {
+	return h.resolveReview(ctx, "RejectReview", uuid, order.StatusRejected)
+}
+
+func (h Handler) resolveReview(ctx echo.Context, name string, uuid string, status string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), name, "request")
+	defer span.End()
+
+	updated, err := h.orderStorage.UpdateStatus(spanCtx, uuid, order.StatusReview, status)
+	if err != nil {
+		h.log(ctx).Error("error on resolving review", zap.String("order_id", uuid), zap.String("status", status), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	if !updated {
+		o, err := h.orderStorage.Get(spanCtx, uuid)
+		if err != nil {
+			h.log(ctx).Error("error on getting order by id", zap.String("order_id", uuid), zap.Error(err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+		if o == nil {
+			return ctx.NoContent(http.StatusNotFound)
+		}
+		return ctx.NoContent(http.StatusConflict)
+	}
+
+	if status == order.StatusRejected {
+		h.releasePromo(spanCtx, uuid)
+	}
+
+	h.log(ctx).Info("review resolved", zap.String("order_id", uuid), zap.String("status", status))
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// errAssessed is order.ErrAssessed, for CheckoutCart whose order shadows
+// the package.
+var errAssessed = order.ErrAssessed
+
+// assessRisk runs the risk check for a new order and records the decision
+// on it. Denied orders are rejected, orders needing a review are held; an
+// order assessed or moved on meanwhile fails with order.ErrAssessed.
+func (h Handler) assessRisk(ctx echo.Context, spanCtx context.Context, o *order.Order, cardNumber string) (*risk.Assessment, error) {
+	disputes, err := h.disputeStorage.Stats(spanCtx, o.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := h.cardFingerprint(cardNumber)
+	assessment, err := h.riskEvaluator.Evaluate(spanCtx, risk.Input{
+		UserId:          o.UserId,
+		OrderId:         o.Id,
+		Amount:          o.Total,
+		Currency:        o.Currency,
+		CardNumber:      cardNumber,
+		CardFingerprint: fingerprint,
+		IP:              ctx.RealIP(),
+		OpenDisputes:    disputes.Open,
+		LostDisputes:    disputes.Lost,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := order.StatusReady
+	switch assessment.Decision {
+	case risk.Deny:
+		status = order.StatusRejected
+	case risk.Review:
+		status = order.StatusReview
+	}
+
+	if err := h.orderStorage.SetRisk(spanCtx, o.Id, status, string(assessment.Decision), assessment.Reasons, fingerprint); err != nil {
+		return nil, err
+	}
+
+	decision := string(assessment.Decision)
+	o.Status = status
+	o.RiskDecision = &decision
+	o.RiskReasons = assessment.Reasons
+	o.CardFingerprint = fingerprint
+	return assessment, nil
+}
+
+// findApprovedOrder returns the approved order of the user if it holds the
+// same items as the cart being checked out, is a gift only if the checkout
+// is one, and is paid with the card that was reviewed. Another card goes
+// through the risk check with a new order.
+func (h Handler) findApprovedOrder(spanCtx context.Context, userId string, items []order.Item, gift bool, cardNumber string) (*order.Order, error) {
+	approved, err := h.orderStorage.FindApproved(spanCtx, userId)
+	if err != nil || approved == nil {
+		return nil, err
+	}
+
+	if !sameItems(approved.Items, items) || (approved.Gift != nil) != gift {
+		return nil, nil
+	}
+	if approved.CardFingerprint == "" || approved.CardFingerprint != h.cardFingerprint(cardNumber) {
+		return nil, nil
+	}
+	return approved, nil
+}
+
+// cardFingerprint identifies the card, it is empty when the risk check is
+// disabled and no order is held for a review.
+func (h Handler) cardFingerprint(cardNumber string) string {
+	if h.fingerprinter == nil {
+		return ""
+	}
+	return h.fingerprinter.Card(cardNumber)
+}
+
+func sameItems(a, b []order.Item) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	ids := func(items []order.Item) []string {
+		output := []string{}
+		for _, item := range items {
+			output = append(output, item.Id)
+		}
+		sort.Strings(output)
+		return output
+	}
+
+	aIds, bIds := ids(a), ids(b)
+	for i := range aIds {
+		if aIds[i] != bIds[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,54 +1,252 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"orderservice/pkg/config"
+	"orderservice/pkg/ratelimit"
 	"orderservice/pkg/server/gen"
+	"orderservice/pkg/serviceauth"
+	"orderservice/pkg/telemetry"
+	"strings"
+	"sync"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"go.elastic.co/apm/module/apmechov4/v2"
 )
 
 type Server struct {
-	handler *Handler
-	config  *config.Config
+	handler     *Handler
+	config      *config.Config
+	echo        *echo.Echo
+	rateLimiter ratelimit.Limiter
+
+	// the mTLS server, echo only drains the servers it created itself
+	mu        sync.Mutex
+	tlsServer *http.Server
 }
 
-func NewServer(handler *Handler, conf *config.Config) *Server {
+// NewServer creates the server; rateLimiter may be nil to disable rate
+// limiting.
+func NewServer(handler *Handler, conf *config.Config, rateLimiter ratelimit.Limiter) *Server {
 	return &Server{
-		handler: handler,
-		config:  conf,
+		handler:     handler,
+		config:      conf,
+		echo:        echo.New(),
+		rateLimiter: rateLimiter,
 	}
 }
 
+// Shutdown stops accepting new connections and waits for the in-flight
+// requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	tlsServer := s.tlsServer
+	s.mu.Unlock()
+
+	if tlsServer != nil {
+		if err := tlsServer.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	return s.echo.Shutdown(ctx)
+}
+
 func (s *Server) Listen() error {
-	e := echo.New()
+	e := s.echo
+
+	proxies, err := parseProxies(s.config.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+	e.IPExtractor = ipExtractor(proxies)
 
 	e.Use(middleware.Recover())
-	e.Use(apmechov4.Middleware())
+	if s.config.TaxIPCountryHeader != "" {
+		e.Use(DropUntrustedHeaders(proxies, s.config.TaxIPCountryHeader))
+	}
+	e.Use(telemetry.Default().Middleware())
+	e.Use(RequestLogger(s.handler.logger))
+	e.Use(RequestMetrics(s.handler.metrics))
+
+	if exporter, ok := s.handler.metrics.(interface{ Handler() http.Handler }); ok {
+		e.GET("/metrics", echo.WrapHandler(exporter.Handler()))
+	}
+
+	keys, err := s.keySet()
+	if err != nil {
+		return fmt.Errorf("loading jwks: %w", err)
+	}
+	if keys != nil {
+		e.Use(JWTAuth(JWTAuthConfig{
+			Skipper:     PathPrefixSkipper(s.config.JWTAuthPrefixes),
+			Keys:        keys,
+			Issuer:      s.config.JWTIssuer,
+			Audience:    s.config.JWTAudience,
+			UserIdClaim: s.config.JWTUserIdClaim,
+			Leeway:      s.config.JWTLeeway,
+		}))
+	} else if s.config.JWTRequired {
+		return errors.New("jwt authentication needs JWT_JWKS_URL or JWT_JWKS_FILE, set JWT_REQUIRED=false to trust x-user-id")
+	} else {
+		s.handler.logger.Warn("jwt authentication is disabled, x-user-id header is trusted")
+	}
+
+	verifier, err := s.serviceVerifier()
+	if err != nil {
+		return fmt.Errorf("service auth: %w", err)
+	}
+	if verifier != nil {
+		e.Use(ServiceAuth(ServiceAuthConfig{
+			Skipper:  PathPrefixSkipper(s.config.ServiceAuthPrefixes),
+			Verifier: verifier,
+		}))
+	} else {
+		s.handler.logger.Warn("service authentication is disabled, private routes are open")
+	}
+
+	if s.rateLimiter != nil {
+		policies, err := ratelimit.ParsePolicies(s.config.RateLimitPolicies)
+		if err != nil {
+			return fmt.Errorf("rate limit policies: %w", err)
+		}
+		e.Use(RateLimit(RateLimitConfig{
+			Skipper:  probeSkipper,
+			Limiter:  s.rateLimiter,
+			Policies: policies,
+		}))
+	}
 
 	swagger, err := gen.GetSwagger()
 	if err != nil {
-		e.Logger.Fatal("openapi3 error", err)
-		return err
+		return fmt.Errorf("loading openapi3 spec: %w", err)
 	}
 
 	jsonByte, err := swagger.MarshalJSON()
 	if err != nil {
-		e.Logger.Fatal("openapi3 serialization error", err)
-		return err
+		return fmt.Errorf("serializing openapi3 spec: %w", err)
 	}
 
 	e.GET("/_openapi3.json", func(c echo.Context) error {
 		return c.JSONBlob(http.StatusOK, jsonByte)
 	})
 
+	validator, err := OpenAPIValidator(ValidationConfig{
+		Swagger:            swagger,
+		Logger:             s.handler.logger,
+		ResponseValidation: s.config.ResponseValidation,
+	})
+	if err != nil {
+		return fmt.Errorf("openapi3 validator: %w", err)
+	}
+	e.Use(validator)
+
 	gen.RegisterHandlers(e, s.handler)
 
-	if err := e.Start(s.config.ListenAddr); err != nil {
-		e.Logger.Fatal(err)
+	if s.config.ServiceAuthMode == "mtls" {
+		err = s.startTLS(e)
+	} else {
+		err = e.Start(s.config.ListenAddr)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		e.Logger.Error(err)
 		return err
 	}
 	return nil
 }
+
+func (s *Server) startTLS(e *echo.Echo) error {
+	tlsConfig, err := serviceauth.ServerTLSConfig(s.config.ServiceMTLSCAFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.config.TLSCertFile, s.config.TLSKeyFile)
+	if err != nil {
+		return err
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+
+	server := &http.Server{
+		Addr:      s.config.ListenAddr,
+		TLSConfig: tlsConfig,
+	}
+	s.mu.Lock()
+	s.tlsServer = server
+	s.mu.Unlock()
+
+	return e.StartServer(server)
+}
+
+// ipExtractor reads the client ip from the X-Forwarded-For set by the
+// trusted proxies, and takes the peer address when there are none; echo
+// trusts the headers of any private address otherwise.
+func ipExtractor(proxies []*net.IPNet) echo.IPExtractor {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, ipRange := range proxies {
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// parseProxies reads the addresses and networks of the trusted proxies.
+func parseProxies(proxies []string) ([]*net.IPNet, error) {
+	output := []*net.IPNet{}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, ipRange)
+	}
+	return output, nil
+}
+
+func (s *Server) keySet() (KeySet, error) {
+	if s.config.JWTJWKSFile != "" {
+		return LoadJWKSFile(s.config.JWTJWKSFile)
+	}
+	if s.config.JWTJWKSUrl != "" {
+		return NewRemoteKeySet(s.config.JWTJWKSUrl, s.config.JWTJWKSTTL), nil
+	}
+	return nil, nil
+}
+
+func (s *Server) serviceVerifier() (serviceauth.Verifier, error) {
+	switch s.config.ServiceAuthMode {
+	case "", "none":
+		return nil, nil
+	case "hmac":
+		if len(s.config.ServiceHMACKeys) == 0 {
+			return nil, errors.New("hmac service auth needs SERVICE_HMAC_KEYS")
+		}
+		return serviceauth.NewHMACVerifier(s.config.ServiceHMACKeys, s.config.ServiceHMACMaxSkew), nil
+	case "mtls":
+		if s.config.ServiceMTLSCAFile == "" || s.config.TLSCertFile == "" || s.config.TLSKeyFile == "" {
+			return nil, errors.New("mtls service auth needs SERVICE_MTLS_CA_FILE, TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return serviceauth.NewMTLSVerifier(s.config.ServiceMTLSAllowed), nil
+	default:
+		return nil, fmt.Errorf("unknown service auth mode %q", s.config.ServiceAuthMode)
+	}
+}
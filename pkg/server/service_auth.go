@@ -0,0 +1,46 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"orderservice/pkg/serviceauth"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const serviceIdContextKey = "service_id"
+
+type ServiceAuthConfig struct {
+	// Skipper defines a function to skip the middleware, used to limit it
+	// to the private route group.
+	Skipper middleware.Skipper
+
+	Verifier serviceauth.Verifier
+}
+
+// ServiceAuth only lets through requests made by an authenticated service.
+func ServiceAuth(config ServiceAuthConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			serviceId, err := config.Verifier.Verify(c.Request())
+			if err != nil {
+				if errors.Is(err, serviceauth.ErrNotAllowed) {
+					return echo.NewHTTPError(http.StatusForbidden, "service is not allowed")
+				}
+				return echo.NewHTTPError(http.StatusUnauthorized, "service is not authenticated")
+			}
+
+			c.Set(serviceIdContextKey, serviceId)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/tax"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DropUntrustedHeaders removes headers only the trusted proxies may set,
+// like the IP country header of the CDN, from requests sent by anyone else.
+func DropUntrustedHeaders(proxies []*net.IPNet, headers ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !fromProxy(c.Request().RemoteAddr, proxies) {
+				for _, header := range headers {
+					c.Request().Header.Del(header)
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+func fromProxy(remoteAddr string, proxies []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipRange := range proxies {
+		if ipRange.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// taxEvidence collects where the customer is from the billing country sent
+// with the card and the IP country header. The country is only set when
+// both agree, one of them alone doesn't prove where the customer is.
+func (h Handler) taxEvidence(ctx echo.Context, billingCountry *string) order.TaxEvidence {
+	evidence := order.TaxEvidence{}
+	if billingCountry != nil {
+		evidence.BillingCountry = strings.ToUpper(strings.TrimSpace(*billingCountry))
+	}
+	if h.ipCountryHeader != "" {
+		// "XX" and "T1" are sent by CDNs for unknown and Tor addresses
+		ipCountry := strings.ToUpper(strings.TrimSpace(ctx.Request().Header.Get(h.ipCountryHeader)))
+		if len(ipCountry) == 2 && ipCountry != "XX" && ipCountry != "T1" {
+			evidence.IPCountry = ipCountry
+		}
+	}
+
+	if evidence.BillingCountry != "" && evidence.BillingCountry == evidence.IPCountry {
+		evidence.Country = evidence.BillingCountry
+	}
+	return evidence
+}
+
+// calculateTax returns the tax lines of amount sold to the customer and the
+// total to charge. A customer whose country isn't established is only
+// served when none of the countries given is taxed, reported as
+// tax.ErrInsufficientEvidence otherwise.
+func (h Handler) calculateTax(evidence order.TaxEvidence, amount float32) ([]order.TaxLine, float32, error) {
+	taxes := []order.TaxLine{}
+	if evidence.Country == "" {
+		for _, country := range []string{evidence.BillingCountry, evidence.IPCountry} {
+			if country == "" {
+				continue
+			}
+			line, err := h.taxCalculator.Calculate(country, amount)
+			if err != nil {
+				return taxes, amount, err
+			}
+			if line != nil {
+				return taxes, amount, tax.ErrInsufficientEvidence
+			}
+		}
+		return taxes, amount, nil
+	}
+
+	line, err := h.taxCalculator.Calculate(evidence.Country, amount)
+	if err != nil || line == nil {
+		return taxes, amount, err
+	}
+
+	taxes = append(taxes, order.TaxLine{
+		Country:   line.Country,
+		Rate:      line.Rate,
+		Net:       line.Net,
+		Amount:    line.Amount,
+		Inclusive: line.Inclusive,
+	})
+	if !line.Inclusive {
+		amount += line.Amount
+	}
+	return taxes, amount, nil
+}
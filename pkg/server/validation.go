@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
+)
+
+const (
+	ResponseValidationOff     = "off"
+	ResponseValidationLog     = "log"
+	ResponseValidationEnforce = "enforce"
+)
+
+func init() {
+	openapi3.DefineStringFormatCallback("uuid", func(value string) error {
+		_, err := uuid.Parse(value)
+		return err
+	})
+
+	// rendered documents are only checked for their content type
+	openapi3filter.RegisterBodyDecoder("application/pdf", openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.FileBodyDecoder)
+}
+
+type ValidationConfig struct {
+	Skipper middleware.Skipper
+
+	Swagger *openapi3.T
+	Logger  *zap.Logger
+
+	// ResponseValidation is one of off, log or enforce. log reports
+	// responses drifting from the spec, enforce also replaces them with a
+	// 500 and is meant for tests and staging.
+	ResponseValidation string
+}
+
+type ValidationError struct {
+	In     string `json:"in"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type ValidationErrorResponse struct {
+	Message string            `json:"message"`
+	Errors  []ValidationError `json:"errors"`
+}
+
+// OpenAPIValidator validates requests against the spec before they reach
+// the handlers. Requests to routes the spec doesn't know are passed through.
+func OpenAPIValidator(config ValidationConfig) (echo.MiddlewareFunc, error) {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
+
+	// servers would make the router match on host as well
+	config.Swagger.Servers = nil
+	router, err := gorillamux.NewRouter(config.Swagger)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			route, pathParams, err := router.FindRoute(req)
+			if err != nil {
+				return next(c)
+			}
+
+			requestInput := &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+			if err := openapi3filter.ValidateRequest(req.Context(), requestInput); err != nil {
+				return c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+					Message: "invalid request",
+					Errors:  validationErrors(err),
+				})
+			}
+
+			if config.ResponseValidation != ResponseValidationLog && config.ResponseValidation != ResponseValidationEnforce {
+				return next(c)
+			}
+			// a stream has to be flushed as it is written, it can't be
+			// recorded and checked once complete
+			if streams(route) {
+				return next(c)
+			}
+
+			return validateResponse(c, next, config, requestInput, route)
+		}
+	}, nil
+}
+
+func validateResponse(c echo.Context, next echo.HandlerFunc, config ValidationConfig, requestInput *openapi3filter.RequestValidationInput, route *routers.Route) error {
+	res := c.Response()
+	original := res.Writer
+	recorder := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+	res.Writer = recorder
+
+	err := next(c)
+	res.Writer = original
+	if err != nil {
+		// the error handler writes the response, nothing was recorded
+		return err
+	}
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 recorder.status,
+		Header:                 recorder.header,
+		Options:                &openapi3filter.Options{MultiError: true},
+	}
+	responseInput.SetBodyBytes(recorder.body.Bytes())
+
+	if err := openapi3filter.ValidateResponse(c.Request().Context(), responseInput); err != nil {
+		config.Logger.Error("response does not match openapi spec",
+			zap.String("method", route.Method),
+			zap.String("path", route.Path),
+			zap.Int("status", recorder.status),
+			zap.Error(err),
+		)
+
+		if config.ResponseValidation == ResponseValidationEnforce {
+			res.Committed = false
+			return c.JSON(http.StatusInternalServerError, ValidationErrorResponse{
+				Message: "response does not match openapi spec",
+				Errors:  validationErrors(err),
+			})
+		}
+	}
+
+	for key, values := range recorder.header {
+		original.Header()[key] = values
+	}
+	original.WriteHeader(recorder.status)
+	_, err = original.Write(recorder.body.Bytes())
+	return err
+}
+
+func streams(route *routers.Route) bool {
+	response := route.Operation.Responses.Get(http.StatusOK)
+	return response != nil && response.Value != nil && response.Value.Content.Get("text/event-stream") != nil
+}
+
+func validationErrors(err error) []ValidationError {
+	output := []ValidationError{}
+	collectValidationErrors(err, ValidationError{In: "request"}, &output)
+	return output
+}
+
+// collectValidationErrors flattens the nested kin-openapi errors, keeping
+// track of where in the request or response the failure happened.
+func collectValidationErrors(err error, base ValidationError, output *[]ValidationError) {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		for _, inner := range e {
+			collectValidationErrors(inner, base, output)
+		}
+	case *openapi3filter.RequestError:
+		if e.Parameter != nil {
+			base.In = e.Parameter.In
+			base.Name = e.Parameter.Name
+		} else if e.RequestBody != nil {
+			base.In = "body"
+		}
+		if e.Err == nil {
+			base.Reason = e.Reason
+			*output = append(*output, base)
+			return
+		}
+		collectValidationErrors(e.Err, base, output)
+	case *openapi3filter.ResponseError:
+		base.In = "response"
+		if e.Err == nil {
+			base.Reason = e.Reason
+			*output = append(*output, base)
+			return
+		}
+		collectValidationErrors(e.Err, base, output)
+	case *openapi3.SchemaError:
+		if pointer := e.JSONPointer(); len(pointer) > 0 {
+			base.Name = strings.Join(pointer, ".")
+		}
+		base.Reason = e.Reason
+		*output = append(*output, base)
+	default:
+		base.Reason = err.Error()
+		*output = append(*output, base)
+	}
+}
+
+// responseRecorder holds the response back until it is validated.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/repo/wallet"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (h Handler) GetWallet(ctx echo.Context, params gen.GetWalletParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "GetWallet", "request")
+	defer span.End()
+
+	balance, err := h.walletStorage.Balance(spanCtx, params.XUserId)
+	if err != nil {
+		h.log(ctx).Error("error on getting wallet balance", zap.String("user_id", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := gen.WalletBalance{
+		Balance:  balance.Amount,
+		Currency: "EUR",
+		Expiring: []gen.WalletExpiring{},
+	}
+	for _, e := range balance.Expiring {
+		output.Expiring = append(output.Expiring, gen.WalletExpiring{Amount: e.Amount, ExpiresAt: e.ExpiresAt})
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) ListWalletEntries(ctx echo.Context, params gen.ListWalletEntriesParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ListWalletEntries", "request")
+	defer span.End()
+
+	opts := wallet.ListOptions{
+		Limit: defaultListLimit,
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxListLimit {
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		opts.Limit = *params.Limit
+	}
+	if params.Cursor != nil {
+		cursor, err := wallet.DecodeCursor(*params.Cursor)
+		if err != nil {
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		opts.Cursor = cursor
+	}
+
+	page, err := h.walletStorage.List(spanCtx, params.XUserId, opts)
+	if err != nil {
+		h.log(ctx).Error("error on listing wallet entries for user", zap.String("user_id", params.XUserId), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	output := gen.WalletHistory{
+		Entries: []gen.WalletEntry{},
+	}
+	for i := range page.Entries {
+		output.Entries = append(output.Entries, toGenWalletEntry(&page.Entries[i]))
+	}
+	if page.NextCursor != nil {
+		next := page.NextCursor.Encode()
+		output.NextCursor = &next
+	}
+
+	return ctx.JSON(http.StatusOK, output)
+}
+
+func (h Handler) CreditWallet(ctx echo.Context, user string) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "CreditWallet", "request")
+	defer span.End()
+
+	request := new(gen.WalletCreditRequest)
+	if err := ctx.Bind(request); err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	if !isUUID(user) || request.Amount <= 0 || (request.OrderId != nil && !isUUID(*request.OrderId)) {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	entry, err := h.walletStorage.Credit(spanCtx, wallet.Credit{
+		UserId:    user,
+		Amount:    request.Amount,
+		Reason:    request.Reason,
+		OrderId:   request.OrderId,
+		ExpiresAt: request.ExpiresAt,
+	})
+	if err != nil {
+		h.log(ctx).Error("error on crediting wallet", zap.String("user_id", user), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	h.log(ctx).Info("wallet credited", zap.String("user_id", user), zap.Float32("amount", entry.Amount), zap.String("reason", entry.Reason))
+	return ctx.JSON(http.StatusCreated, toGenWalletEntry(entry))
+}
+
+// reverseWallet gives the store credit spent on an order that won't be paid
+// back to the user. Failures are only logged, the order is not paid either
+// way.
+func (h Handler) reverseWallet(spanCtx context.Context, orderId string) {
+	log := logging.FromContext(spanCtx).With(zap.String("order_id", orderId))
+	if _, err := h.walletStorage.Reverse(spanCtx, orderId, "order not paid"); err != nil {
+		log.Error("error on reversing wallet debit", zap.Error(err))
+		return
+	}
+	// a paid order keeps how it was paid
+	if err := h.orderStorage.SetWalletAmount(spanCtx, orderId, 0); err != nil && !errors.Is(err, order.ErrNotPayable) {
+		log.Error("error on clearing the store credit spent", zap.Error(err))
+	}
+}
+
+func toGenWalletEntry(e *wallet.Entry) gen.WalletEntry {
+	return gen.WalletEntry{
+		Id:        e.Id,
+		Kind:      gen.WalletEntryKind(e.Kind),
+		Amount:    e.Amount,
+		OrderId:   e.OrderId,
+		Reason:    e.Reason,
+		ExpiresAt: e.ExpiresAt,
+		CreatedAt: e.CreatedAt,
+	}
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"orderservice/pkg/client/payment"
+	"orderservice/pkg/logging"
+	"orderservice/pkg/repo/dispute"
+	"orderservice/pkg/repo/order"
+	"orderservice/pkg/server/gen"
+	"orderservice/pkg/telemetry"
+	"orderservice/pkg/webhook"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// webhookLease is how long a delivery holds its event, longer than any
+// processing takes.
+const webhookLease = time.Minute
+
+func (h Handler) ReceivePaymentWebhook(ctx echo.Context, params gen.ReceivePaymentWebhookParams) error {
+	span, spanCtx := telemetry.StartSpan(ctx.Request().Context(), "ReceivePaymentWebhook", "request")
+	defer span.End()
+
+	if h.webhookVerifier == nil {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	// the signature covers the body as sent, it is read before decoding
+	body, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	if err := h.webhookVerifier.Verify(params.XPaymentSignature, body, time.Now()); err != nil {
+		h.log(ctx).Warn("payment webhook refused", zap.Error(err))
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	event := webhook.Event{}
+	if err := json.Unmarshal(body, &event); err != nil || event.Id == "" {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	log := h.log(ctx).With(zap.String("event_id", event.Id), zap.String("type", event.Type), zap.String("order_id", event.OrderId))
+
+	outcome, err := h.webhookStorage.Claim(spanCtx, event, body, webhookLease)
+	if errors.Is(err, webhook.ErrInProgress) {
+		return ctx.NoContent(http.StatusConflict)
+	}
+	if err != nil {
+		log.Error("error on claiming payment event", zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	if outcome != "" {
+		log.Info("payment event already processed", zap.String("outcome", outcome))
+		return ctx.JSON(http.StatusOK, gen.PaymentWebhookResult{EventId: event.Id, Outcome: gen.PaymentWebhookResultOutcome(outcome), Duplicate: true})
+	}
+
+	outcome, err = h.applyPaymentEvent(spanCtx, event)
+	if err != nil {
+		log.Error("error on processing payment event", zap.Error(err))
+		if err := h.webhookStorage.Release(spanCtx, event.Id); err != nil {
+			log.Error("error on releasing payment event", zap.Error(err))
+		}
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	// the transitions are idempotent, an event processed again after failing
+	// to record its outcome changes nothing
+	if err := h.webhookStorage.Processed(spanCtx, event.Id, outcome); err != nil {
+		log.Error("error on recording payment event outcome", zap.String("outcome", outcome), zap.Error(err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	log.Info("payment event processed", zap.String("outcome", outcome))
+	return ctx.JSON(http.StatusOK, gen.PaymentWebhookResult{EventId: event.Id, Outcome: gen.PaymentWebhookResultOutcome(outcome), Duplicate: false})
+}
+
+// applyPaymentEvent moves the order of the event and returns what it did.
+// Events that don't fit the status of the order are ignored, reconciliation
+// reports the payments left without a matching order. A capture completes
+// the order only when it is the card leg recorded for it with the store
+// credit spent on it still spent, and a failure only fails the order when
+// it is the latest attempt to pay it.
+func (h Handler) applyPaymentEvent(spanCtx context.Context, event webhook.Event) (string, error) {
+	log := logging.FromContext(spanCtx).With(zap.String("event_id", event.Id), zap.String("order_id", event.OrderId))
+
+	if !isUUID(event.OrderId) {
+		log.Warn("payment event for an unknown order")
+		return webhook.OutcomeIgnored, nil
+	}
+	o, err := h.orderStorage.Get(spanCtx, event.OrderId)
+	if err != nil {
+		return "", err
+	}
+	if o == nil {
+		log.Warn("payment event for an unknown order")
+		return webhook.OutcomeIgnored, nil
+	}
+
+	switch event.Type {
+	case webhook.TypeCaptured:
+		switch o.Status {
+		case order.StatusReady, order.StatusApproved:
+			if !o.ChargedLeg(float32(event.Amount), event.Currency) {
+				log.Warn("payment captured for another amount", zap.String("payment_id", event.PaymentId))
+				return webhook.OutcomeIgnored, nil
+			}
+			reversed, err := h.walletStorage.Reversed(spanCtx, o.Id)
+			if err != nil {
+				return "", err
+			}
+			if reversed > 0 {
+				log.Warn("payment captured after the store credit was given back", zap.String("payment_id", event.PaymentId))
+				return webhook.OutcomeIgnored, nil
+			}
+
+			// an order moved meanwhile fails with order.ErrNotPayable, the
+			// event is applied to the new status by the next delivery
+			charge := order.Payment{Id: event.PaymentId, Amount: float32(event.Amount), Currency: event.Currency}
+			if err := h.orderStorage.Complete(spanCtx, o.Id, charge); err != nil {
+				return "", err
+			}
+			h.metrics.CheckoutSucceeded(event.Currency, event.Amount)
+			return webhook.OutcomeCompleted, nil
+		case order.StatusCompleted:
+			if o.PaymentId == nil || *o.PaymentId != event.PaymentId {
+				log.Warn("payment captured for an order paid otherwise", zap.String("payment_id", event.PaymentId))
+				return webhook.OutcomeIgnored, nil
+			}
+			return webhook.OutcomeUnchanged, nil
+		}
+
+	case webhook.TypeFailed:
+		switch o.Status {
+		case order.StatusReady, order.StatusApproved:
+			latest, err := h.latestAttempt(spanCtx, o.Id)
+			if err != nil {
+				return "", err
+			}
+			if latest == "" || latest != event.PaymentId {
+				log.Warn("payment failed for an earlier attempt", zap.String("payment_id", event.PaymentId))
+				return webhook.OutcomeIgnored, nil
+			}
+
+			moved, err := h.orderStorage.UpdateStatus(spanCtx, o.Id, o.Status, order.StatusFailed)
+			if err != nil {
+				return "", err
+			}
+			// moved meanwhile, the event is applied to the new status by the
+			// next delivery
+			if !moved {
+				return "", errors.New("order moved while failing it")
+			}
+			h.metrics.CheckoutDeclined()
+			h.reverseWallet(spanCtx, o.Id)
+			h.releasePromo(spanCtx, o.Id)
+			return webhook.OutcomeFailed, nil
+		case order.StatusFailed:
+			return webhook.OutcomeUnchanged, nil
+		}
+
+	case webhook.TypeChargeback:
+		// the order stays completed until the bank decides, its products
+		// are suspended meanwhile
+		open, err := h.disputeStorage.FindOpen(spanCtx, o.Id)
+		if err != nil {
+			return "", err
+		}
+		if open != nil {
+			return webhook.OutcomeUnchanged, nil
+		}
+		if o.Status != order.StatusCompleted {
+			break
+		}
+
+		opening := dispute.Opening{OrderId: o.Id, PaymentId: event.PaymentId, Reason: event.Reason, Amount: float32(event.Amount), Currency: event.Currency}
+		if _, err := h.disputeStorage.Open(spanCtx, opening); err != nil {
+			return "", err
+		}
+		return webhook.OutcomeDisputed, nil
+
+	case webhook.TypeDisputeWon, webhook.TypeDisputeLost:
+		open, err := h.disputeStorage.FindOpen(spanCtx, o.Id)
+		if err != nil {
+			return "", err
+		}
+		if open == nil {
+			break
+		}
+
+		state, outcome := dispute.StateWon, webhook.OutcomeDisputeWon
+		if event.Type == webhook.TypeDisputeLost {
+			state, outcome = dispute.StateLost, webhook.OutcomeChargedBack
+		}
+		if _, err := h.disputeStorage.Resolve(spanCtx, open.Id, state); err != nil {
+			return "", err
+		}
+		return outcome, nil
+
+	default:
+		log.Info("payment event of unknown type ignored")
+		return webhook.OutcomeIgnored, nil
+	}
+
+	log.Warn("payment event ignored for the order status", zap.String("status", o.Status))
+	return webhook.OutcomeIgnored, nil
+}
+
+// latestAttempt returns the id of the latest payment made for an order, none
+// when one of them was captured: the order is paid and no failure applies to
+// it.
+func (h Handler) latestAttempt(spanCtx context.Context, orderId string) (string, error) {
+	payments, err := h.paymentClient.FindPayments(spanCtx, orderId)
+	if err != nil {
+		return "", err
+	}
+
+	var latest *payment.Payment
+	for i, p := range payments {
+		if p.Status == payment.StatusCaptured {
+			return "", nil
+		}
+		if latest == nil || p.CreatedAt.After(latest.CreatedAt) {
+			latest = &payments[i]
+		}
+	}
+	if latest == nil {
+		return "", nil
+	}
+	return latest.Id, nil
+}
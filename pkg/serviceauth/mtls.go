@@ -0,0 +1,70 @@
+package serviceauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+)
+
+// MTLSVerifier accepts requests whose client certificate was verified
+// against the configured CA pool during the tls handshake and whose
+// identity is in the allowlist. The identity is the first URI SAN (e.g. a
+// spiffe id) or the common name when there is none.
+type MTLSVerifier struct {
+	allowed map[string]bool
+}
+
+func NewMTLSVerifier(allowed []string) *MTLSVerifier {
+	allowedMap := map[string]bool{}
+	for _, identity := range allowed {
+		allowedMap[identity] = true
+	}
+
+	return &MTLSVerifier{
+		allowed: allowedMap,
+	}
+}
+
+func (v MTLSVerifier) Verify(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", ErrUnauthenticated
+	}
+
+	identity := CertificateIdentity(r.TLS.VerifiedChains[0][0])
+	if !v.allowed[identity] {
+		return "", ErrNotAllowed
+	}
+
+	return identity, nil
+}
+
+func CertificateIdentity(cert *x509.Certificate) string {
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	return cert.Subject.CommonName
+}
+
+// ServerTLSConfig builds the tls config asking clients for a certificate
+// signed by the CA in caFile. Certificates are optional on the handshake
+// level so that public routes keep working without one; the Verifier
+// rejects private requests that didn't present a valid certificate.
+func ServerTLSConfig(caFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificate found in ca file")
+	}
+
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,22 @@
+package serviceauth
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrUnauthenticated = errors.New("request is not authenticated")
+	ErrNotAllowed      = errors.New("service is not allowed")
+)
+
+// Verifier authenticates an incoming request made by another service and
+// returns the identity of the calling service.
+type Verifier interface {
+	Verify(r *http.Request) (string, error)
+}
+
+// Signer authenticates an outgoing request to another service.
+type Signer interface {
+	Sign(r *http.Request) error
+}
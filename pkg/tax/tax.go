@@ -0,0 +1,130 @@
+package tax
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+type Mode string
+
+const (
+	// prices already hold the tax, it is taken out of them
+	Inclusive Mode = "inclusive"
+	// the tax is added on top of the prices
+	Exclusive Mode = "exclusive"
+)
+
+// ErrInsufficientEvidence is returned when the country of a customer who
+// may be taxed isn't backed by two agreeing pieces of evidence.
+var ErrInsufficientEvidence = errors.New("customer country not established")
+
+// the rates of electronically supplied books, reduced or zero where the
+// country allows it
+//
+//go:embed vat_rates.json
+var defaultRates []byte
+
+// Line is the tax charged on an order. Net is the amount the tax is
+// computed on, so Net+Amount is what the customer pays.
+type Line struct {
+	Country   string  `json:"country"`
+	Rate      float32 `json:"rate"`
+	Net       float32 `json:"net"`
+	Amount    float32 `json:"amount"`
+	Inclusive bool    `json:"inclusive"`
+}
+
+// TaxCalculator computes the tax of an amount sold to a customer in
+// country, returning nil when no tax applies.
+type TaxCalculator interface {
+	Calculate(country string, amount float32) (*Line, error)
+}
+
+// Rates are percentages keyed by ISO 3166-1 alpha-2 country code.
+type Rates struct {
+	Rates map[string]float32 `json:"rates"`
+}
+
+// LoadRates reads the rates from path, or the bundled EU VAT rates of
+// e-books when path is empty.
+func LoadRates(path string) (*Rates, error) {
+	data := defaultRates
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rates := new(Rates)
+	if err := json.Unmarshal(data, rates); err != nil {
+		return nil, err
+	}
+
+	for country, rate := range rates.Rates {
+		if len(country) != 2 || strings.ToUpper(country) != country {
+			return nil, fmt.Errorf("invalid country code %q", country)
+		}
+		if rate < 0 || rate >= 100 {
+			return nil, fmt.Errorf("country %s: invalid rate %v", country, rate)
+		}
+	}
+	return rates, nil
+}
+
+// TableCalculator looks the rate up by country. Countries missing from the
+// table are not taxed.
+type TableCalculator struct {
+	rates *Rates
+	mode  Mode
+}
+
+func NewTableCalculator(rates *Rates, mode Mode) (*TableCalculator, error) {
+	if mode != Inclusive && mode != Exclusive {
+		return nil, fmt.Errorf("unknown tax mode %q", mode)
+	}
+
+	c := &TableCalculator{
+		rates: rates,
+		mode:  mode,
+	}
+	return c, nil
+}
+
+func (c TableCalculator) Calculate(country string, amount float32) (*Line, error) {
+	rate, ok := c.rates.Rates[strings.ToUpper(country)]
+	if !ok || rate == 0 {
+		return nil, nil
+	}
+
+	line := &Line{
+		Country:   strings.ToUpper(country),
+		Rate:      rate,
+		Inclusive: c.mode == Inclusive,
+	}
+	if line.Inclusive {
+		line.Net = roundCents(amount / (1 + rate/100))
+		line.Amount = roundCents(amount - line.Net)
+	} else {
+		line.Net = amount
+		line.Amount = roundCents(amount * rate / 100)
+	}
+	return line, nil
+}
+
+// NoTax is used when tax calculation is disabled.
+type NoTax struct{}
+
+func (NoTax) Calculate(country string, amount float32) (*Line, error) {
+	return nil, nil
+}
+
+func roundCents(amount float32) float32 {
+	return float32(math.Round(float64(amount)*100) / 100)
+}
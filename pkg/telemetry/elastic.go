@@ -0,0 +1,55 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.elastic.co/apm/module/apmechov4/v2"
+	"go.elastic.co/apm/module/apmhttp/v2"
+	"go.elastic.co/apm/v2"
+)
+
+// Elastic traces with the Elastic APM agent, configured by the usual
+// ELASTIC_APM_* environment variables.
+type Elastic struct {
+	tracer *apm.Tracer
+}
+
+func NewElastic() *Elastic {
+	return &Elastic{
+		tracer: apm.DefaultTracer(),
+	}
+}
+
+func (e *Elastic) StartSpan(ctx context.Context, name, spanType string) (Span, context.Context) {
+	return apm.StartSpan(ctx, name, spanType)
+}
+
+func (e *Elastic) Middleware() echo.MiddlewareFunc {
+	return apmechov4.Middleware(apmechov4.WithTracer(e.tracer))
+}
+
+func (e *Elastic) Inject(req *http.Request) {
+	ctx := req.Context()
+	if span := apm.SpanFromContext(ctx); span != nil {
+		apmhttp.SetHeaders(req, span.TraceContext(), false)
+		return
+	}
+	if tx := apm.TransactionFromContext(ctx); tx != nil {
+		apmhttp.SetHeaders(req, tx.TraceContext(), false)
+	}
+}
+
+func (e *Elastic) TraceId(ctx context.Context) string {
+	if tx := apm.TransactionFromContext(ctx); tx != nil {
+		return tx.TraceContext().Trace.String()
+	}
+	return ""
+}
+
+func (e *Elastic) Shutdown(ctx context.Context) error {
+	e.tracer.Flush(ctx.Done())
+	e.tracer.Close()
+	return nil
+}
@@ -0,0 +1,34 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type nopSpan struct{}
+
+func (nopSpan) End() {}
+
+type Nop struct{}
+
+func (Nop) StartSpan(ctx context.Context, name, spanType string) (Span, context.Context) {
+	return nopSpan{}, ctx
+}
+
+func (Nop) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return next
+	}
+}
+
+func (Nop) Inject(req *http.Request) {}
+
+func (Nop) TraceId(ctx context.Context) string {
+	return ""
+}
+
+func (Nop) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterMemory = "memory"
+)
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+// OTel traces with the OpenTelemetry SDK. The exporter decides where spans
+// go; the otlp exporter reads the standard OTEL_EXPORTER_OTLP_* variables.
+type OTel struct {
+	serviceName string
+	provider    *sdktrace.TracerProvider
+	tracer      trace.Tracer
+	propagator  propagation.TextMapPropagator
+}
+
+func NewOTel(serviceName string, exporter sdktrace.SpanExporter) *OTel {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+
+	return &OTel{
+		serviceName: serviceName,
+		provider:    provider,
+		tracer:      provider.Tracer("orderservice"),
+		propagator:  propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+}
+
+// NewOTelExporter creates one of the otlp, stdout or memory exporters. The
+// memory exporter keeps spans for inspection in tests.
+func NewOTelExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case ExporterStdout:
+		return stdouttrace.New()
+	case ExporterMemory:
+		return tracetest.NewInMemoryExporter(), nil
+	default:
+		return otlptracehttp.New(ctx)
+	}
+}
+
+func (o *OTel) StartSpan(ctx context.Context, name, spanType string) (Span, context.Context) {
+	ctx, span := o.tracer.Start(ctx, name, trace.WithAttributes(attribute.String("span.type", spanType)))
+	return otelSpan{span: span}, ctx
+}
+
+func (o *OTel) Middleware() echo.MiddlewareFunc {
+	return otelecho.Middleware(o.serviceName,
+		otelecho.WithTracerProvider(o.provider),
+		otelecho.WithPropagators(o.propagator),
+	)
+}
+
+func (o *OTel) Inject(req *http.Request) {
+	o.propagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+}
+
+func (o *OTel) TraceId(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+func (o *OTel) Shutdown(ctx context.Context) error {
+	return o.provider.Shutdown(ctx)
+}
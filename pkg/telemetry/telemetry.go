@@ -0,0 +1,54 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Span interface {
+	End()
+}
+
+// Tracer hides the tracing backend from handlers, storages and clients.
+type Tracer interface {
+	StartSpan(ctx context.Context, name, spanType string) (Span, context.Context)
+	// Middleware starts a server side transaction for every request and
+	// continues the trace of the caller.
+	Middleware() echo.MiddlewareFunc
+	// Inject adds the W3C trace context of the request context to its
+	// headers.
+	Inject(req *http.Request)
+	// TraceId returns the id of the trace in ctx or an empty string.
+	TraceId(ctx context.Context) string
+	Shutdown(ctx context.Context) error
+}
+
+var defaultTracer atomic.Value
+
+func init() {
+	SetDefault(Nop{})
+}
+
+// SetDefault replaces the tracer used by the package level functions.
+func SetDefault(tracer Tracer) {
+	defaultTracer.Store(&tracer)
+}
+
+func Default() Tracer {
+	return *defaultTracer.Load().(*Tracer)
+}
+
+func StartSpan(ctx context.Context, name, spanType string) (Span, context.Context) {
+	return Default().StartSpan(ctx, name, spanType)
+}
+
+func Inject(req *http.Request) {
+	Default().Inject(req)
+}
+
+func TraceId(ctx context.Context) string {
+	return Default().TraceId(ctx)
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"orderservice/pkg/metrics"
+	"orderservice/pkg/telemetry"
+	"time"
+)
+
+type PGEventStorage struct {
+	db *sql.DB
+}
+
+func NewPGEventStorage(url string) (*PGEventStorage, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PGEventStorage{
+		db: db,
+	}
+	return s, nil
+}
+
+func (s PGEventStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s PGEventStorage) PoolStats() metrics.PoolStats {
+	return metrics.SQLPoolStats(s.db.Stats())
+}
+
+func (s PGEventStorage) Claim(ctx context.Context, event Event, payload []byte, lease time.Duration) (string, error) {
+	span, ctx := telemetry.StartSpan(ctx, "Claim", "PGEventStorage")
+	defer span.End()
+
+	// a new event is inserted claimed, a known one is claimed again only if
+	// it was not processed and nobody holds it
+	var claimed string
+	err := s.db.QueryRowContext(ctx, `INSERT INTO payment_webhook_events (event_id, type, payment_id, order_id, payload, locked_until)
+		VALUES ($1, $2, $3, $4, $5, now() + $6 * interval '1 second')
+		ON CONFLICT (event_id) DO UPDATE SET locked_until = EXCLUDED.locked_until, deliveries = payment_webhook_events.deliveries + 1
+		WHERE payment_webhook_events.processed_at IS NULL
+		AND (payment_webhook_events.locked_until IS NULL OR payment_webhook_events.locked_until < now())
+		RETURNING event_id`,
+		event.Id, event.Type, event.PaymentId, event.OrderId, payload, lease.Seconds()).Scan(&claimed)
+	if err == nil {
+		return "", nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	var outcome sql.NullString
+	err = s.db.QueryRowContext(ctx, "UPDATE payment_webhook_events SET deliveries = deliveries + 1 WHERE event_id = $1 RETURNING outcome", event.Id).Scan(&outcome)
+	if err != nil {
+		return "", err
+	}
+	if !outcome.Valid {
+		return "", ErrInProgress
+	}
+	return outcome.String, nil
+}
+
+func (s PGEventStorage) Release(ctx context.Context, eventId string) error {
+	span, ctx := telemetry.StartSpan(ctx, "Release", "PGEventStorage")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, "UPDATE payment_webhook_events SET locked_until = NULL WHERE event_id = $1 AND processed_at IS NULL", eventId)
+	return err
+}
+
+func (s PGEventStorage) Processed(ctx context.Context, eventId string, outcome string) error {
+	span, ctx := telemetry.StartSpan(ctx, "Processed", "PGEventStorage")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, "UPDATE payment_webhook_events SET outcome = $1, processed_at = now(), locked_until = NULL WHERE event_id = $2", outcome, eventId)
+	return err
+}
@@ -0,0 +1,20 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+// EventStorage keeps the events received, so that each is processed once
+// however many times it is delivered.
+type EventStorage interface {
+	// Claim takes the event for processing during lease. It returns the
+	// outcome of the event if it was processed already, and ErrInProgress
+	// while another delivery holds it.
+	Claim(ctx context.Context, event Event, payload []byte, lease time.Duration) (string, error)
+	// Release gives the event up after its processing failed, so that the
+	// next delivery processes it again.
+	Release(ctx context.Context, eventId string) error
+	// Processed records the outcome of the event.
+	Processed(ctx context.Context, eventId string, outcome string) error
+}
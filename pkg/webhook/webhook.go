@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderSignature carries the timestamp and the signatures of a delivery,
+// as "t=<unix>,v1=<hex>"; several v1 are sent while a secret is rotated.
+const HeaderSignature = "X-Payment-Signature"
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrStale is returned for deliveries signed too long ago, which could
+	// be replayed.
+	ErrStale = errors.New("webhook delivery too old")
+	// ErrInProgress is returned while another delivery of the event is
+	// being processed.
+	ErrInProgress = errors.New("webhook event being processed")
+)
+
+const (
+	TypeCaptured   = "payment.captured"
+	TypeFailed     = "payment.failed"
+	TypeChargeback = "payment.chargeback"
+	// the bank decided on the dispute opened by a chargeback
+	TypeDisputeWon  = "payment.dispute_won"
+	TypeDisputeLost = "payment.dispute_lost"
+)
+
+// what processing an event did to its order
+const (
+	OutcomeCompleted  = "completed"
+	OutcomeFailed     = "failed"
+	OutcomeDisputed   = "disputed"
+	OutcomeDisputeWon = "dispute_won"
+	// the dispute was lost
+	OutcomeChargedBack = "charged_back"
+	// the order was already where the event would move it
+	OutcomeUnchanged = "unchanged"
+	// the event doesn't apply to the order, or is of an unknown type
+	OutcomeIgnored = "ignored"
+)
+
+// Event is a payment outcome sent by the payment service after the payment
+// request was answered.
+type Event struct {
+	Id        string    `json:"id"`
+	Type      string    `json:"type"`
+	PaymentId string    `json:"payment_id"`
+	OrderId   string    `json:"order_id"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Verifier checks the signature of the deliveries, made with HMAC-SHA256
+// of "<timestamp>.<body>" and one of the shared secrets.
+type Verifier struct {
+	secrets   [][]byte
+	tolerance time.Duration
+}
+
+func NewVerifier(secrets []string, tolerance time.Duration) *Verifier {
+	byteSecrets := [][]byte{}
+	for _, secret := range secrets {
+		byteSecrets = append(byteSecrets, []byte(secret))
+	}
+
+	return &Verifier{
+		secrets:   byteSecrets,
+		tolerance: tolerance,
+	}
+}
+
+// Verify checks the signature header of the body. Deliveries signed more
+// than the tolerance ago are refused, the event ids seen are kept for
+// longer than that so a replay within the tolerance is caught by its id.
+func (v Verifier) Verify(header string, body []byte, now time.Time) error {
+	var timestamp string
+	signatures := []string{}
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew > v.tolerance || skew < -v.tolerance {
+		return ErrStale
+	}
+
+	for _, secret := range v.secrets {
+		expected := Sign(secret, timestamp, body)
+		for _, signature := range signatures {
+			if hmac.Equal([]byte(expected), []byte(signature)) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Sign returns the hex signature of the body sent at timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
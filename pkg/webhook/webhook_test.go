@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1","type":"payment.captured"}`)
+	sent := strconv.FormatInt(now.Unix(), 10)
+	old := strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)
+	ahead := strconv.FormatInt(now.Add(10*time.Minute).Unix(), 10)
+
+	current := Sign([]byte("current"), sent, body)
+	retired := Sign([]byte("retired"), sent, body)
+	other := Sign([]byte("other"), sent, body)
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		want   error
+	}{
+		{"signed with the current secret", "t=" + sent + ",v1=" + current, body, nil},
+		{"signed with a secret being rotated", "t=" + sent + ",v1=" + retired, body, nil},
+		{"one of several signatures matches", "t=" + sent + ",v1=" + other + ",v1=" + current, body, nil},
+		{"spaces around the parts", "t=" + sent + ", v1=" + current, body, nil},
+		{"signed with an unknown secret", "t=" + sent + ",v1=" + other, body, ErrInvalidSignature},
+		{"body changed", "t=" + sent + ",v1=" + current, []byte(`{"id":"evt_2"}`), ErrInvalidSignature},
+		{"timestamp changed", "t=" + strconv.FormatInt(now.Unix()+1, 10) + ",v1=" + current, body, ErrInvalidSignature},
+		{"signed too long ago", "t=" + old + ",v1=" + Sign([]byte("current"), old, body), body, ErrStale},
+		{"signed too far ahead", "t=" + ahead + ",v1=" + Sign([]byte("current"), ahead, body), body, ErrStale},
+		{"no timestamp", "v1=" + current, body, ErrInvalidSignature},
+		{"no signature", "t=" + sent, body, ErrInvalidSignature},
+		{"timestamp not a number", "t=now,v1=" + current, body, ErrInvalidSignature},
+		{"empty header", "", body, ErrInvalidSignature},
+	}
+
+	v := NewVerifier([]string{"current", "retired"}, 5*time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(tt.header, tt.body, now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}